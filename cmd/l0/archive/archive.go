@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"l0/config"
+	"l0/internal/archive"
+	"l0/internal/models"
+	"l0/internal/repository"
+	"l0/pkg/logger"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const usage = `usage: l0-archive <command>
+
+commands:
+  move -months N | -before DATE   move orders created before the cutoff from the DB to ARCHIVE_DIR
+  get ORDER_UID                   print an archived order
+  restore ORDER_UID...            move archived orders back to the DB`
+
+func main() {
+	logger.SetupLogger(logger.Config{})
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatalf("archive: %v", err)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", usage)
+	}
+
+	cfg, err := config.NewArchiveConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.ArchiveConfig.Dir == "" {
+		return fmt.Errorf("ARCHIVE_DIR is required")
+	}
+	arch, err := archive.Open(cfg.ArchiveConfig.Dir)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	ctx = models.WithAuditSource(ctx, models.AuditSource{Kind: models.AuditSourceSystem, Actor: "l0-archive"})
+
+	switch args[0] {
+	case "move":
+		before, err := parseCutoff(args[1:])
+		if err != nil {
+			return err
+		}
+		repo, err := repository.NewRepository(cfg.DbConfig)
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+		moved, err := arch.MoveOrders(ctx, repo, before, cfg.BatchSize, nil)
+		fmt.Printf("moved %d orders created before %s\n", moved, before.Format(time.RFC3339))
+		return err
+	case "get":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", usage)
+		}
+		order, err := arch.Get(args[1])
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(order)
+	case "restore":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", usage)
+		}
+		repo, err := repository.NewRepository(cfg.DbConfig)
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+		for _, orderUID := range args[1:] {
+			if _, err := arch.RestoreOrder(ctx, repo, orderUID); err != nil {
+				return fmt.Errorf("order %s: %w", orderUID, err)
+			}
+			fmt.Printf("restored %s\n", orderUID)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// parseCutoff возвращает границу date_created из флагов -months или -before
+func parseCutoff(args []string) (time.Time, error) {
+	fs := flag.NewFlagSet("move", flag.ContinueOnError)
+	months := fs.Int("months", 0, "move orders older than N months")
+	before := fs.String("before", "", "move orders created before DATE (YYYY-MM-DD)")
+	if err := fs.Parse(args); err != nil {
+		return time.Time{}, err
+	}
+
+	switch {
+	case *months > 0 && *before == "":
+		return time.Now().AddDate(0, -*months, 0), nil
+	case *months == 0 && *before != "":
+		t, err := time.Parse(time.DateOnly, *before)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", *before)
+		}
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("exactly one of -months or -before is required\n%s", usage)
+	}
+}
@@ -86,28 +86,29 @@ func createTestOrder(counter int) *models.Order {
 			Region:  "Kraiot",
 			Email:   "test@gmail.com",
 		},
+		// Суммы в минимальных единицах валюты: 181700 - 1817.00 USD
 		Payment: models.Payment{
 			Transaction:  fmt.Sprintf("b563feb7b2b84b6test%d", counter),
 			RequestID:    fmt.Sprintf("internal-request-id-%d", counter),
 			Currency:     "USD",
 			Provider:     "wbpay",
-			Amount:       1817 + counter*100,
+			Amount:       int64(181700 + counter*10000),
 			PaymentDt:    int(time.Now().Unix()),
 			Bank:         "alpha",
-			DeliveryCost: 1500,
-			GoodsTotal:   317 + counter*100,
+			DeliveryCost: 150000,
+			GoodsTotal:   int64(31700 + counter*10000),
 			CustomFee:    0,
 		},
 		Items: models.Items{
 			{
 				ChrtID:      9934930 + counter,
 				TrackNumber: fmt.Sprintf("WBILMTESTTRACK%d", counter),
-				Price:       453 + counter*10,
+				Price:       int64(45300 + counter*1000),
 				Rid:         fmt.Sprintf("ab4219087a764ae0btest%d", counter),
 				Name:        fmt.Sprintf("Product %d", counter),
 				Sale:        30,
 				Size:        "0",
-				TotalPrice:  317 + counter*100,
+				TotalPrice:  int64(31700 + counter*10000),
 				NmID:        2389212 + counter,
 				Brand:       "Test Brand",
 				Status:      202,
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"l0/config"
+	"l0/internal/repository"
+	"strconv"
+)
+
+const migrateUsage = `usage: l0 migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down [N]      revert the last N migrations (default 1)
+  to VERSION    migrate up or down to VERSION (0 reverts everything)
+  version       print the current schema version`
+
+// runMigrate выполняет подкоманду l0 migrate
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	cfg, err := config.NewRepositoryConfig()
+	if err != nil {
+		return err
+	}
+	migrator, err := repository.NewMigrator(*cfg)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", migrateUsage)
+		}
+		version, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.To(ctx, uint(version))
+	case "version":
+		version, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("current: %d (dirty: %t), latest: %d\n", version, dirty, migrator.Latest())
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+}
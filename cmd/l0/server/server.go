@@ -22,6 +22,15 @@ import (
 )
 
 func main() {
+	// l0 migrate ... — управление схемой БД без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		logger.SetupLogger(logger.Config{})
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	// Читаем конфиг
 	cfg, err := config.NewConfig()
 	if err != nil {
@@ -32,18 +41,19 @@ func main() {
 	logger.SetupLogger(cfg.LoggerConfig)
 	zap.S().Info("logger initialized")
 
-	// Создаем репозиторий для работы с БД
+	// Создаем репозиторий для работы с БД (подключение проверяется с повторами)
 	repo, err := repository.NewRepository(cfg.DbConfig)
 	if err != nil {
-		zap.S().Errorf("failed to initialize repository: %v", err)
-		panic(err)
+		zap.S().Fatalf("failed to initialize repository: %v", err)
 	}
+	// Пулы закрываются после остановки всех горутин, которые работают с БД
+	defer repo.Close()
 	zap.S().Info("repository initialized")
 
 	// Инициализируем сервис
-	svc, err := service.NewService(repo)
+	svc, err := service.NewService(cfg.CacheConfig, repo)
 	if err != nil {
-		zap.S().Errorf("failed to initialize service: %v", err)
+		zap.S().Fatalf("failed to initialize service: %v", err)
 	}
 	zap.S().Info("service initialized")
 
@@ -59,6 +69,15 @@ func main() {
 
 	// Создаем HTTP сервер
 	httpHandlers := rest.NewHandler(svc)
+	if cfg.ServerConfig.IngestForwardToKafka {
+		// POST /orders отправляет заказы в тот же топик, который читает consumer
+		producer, err := broker.NewProducer(cfg.KafkaConfig)
+		if err != nil {
+			zap.S().Fatalf("failed to create kafka producer: %v", err)
+		}
+		defer producer.Close()
+		httpHandlers.WithForwarder(producer)
+	}
 	server := rest.CreateServer(cfg.ServerConfig, httpHandlers)
 
 	var wg sync.WaitGroup
@@ -88,9 +107,9 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := consumer.ConsumeOrders(ctx, func(order *models.Order) error {
-			// Сохраняем заказ через сервис
-			if err := svc.CreateOrder(ctx, order); err != nil {
+		err := consumer.ConsumeOrders(ctx, func(order *models.Order, raw models.RawOrder) error {
+			// Сохраняем заказ и исходное сообщение через сервис
+			if err := svc.IngestOrder(ctx, order, raw); err != nil {
 				zap.S().Errorf("failed to save order from kafka: %v", err)
 				return err
 			}
@@ -102,6 +121,48 @@ func main() {
 		}
 	}()
 
+	// Периодически сохраняем снимок кеша для быстрого рестарта
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		svc.RunSnapshots(ctx)
+	}()
+
+	// Сверяем кеш с БД, чтобы подхватить изменения других процессов (например, l0-archive)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		svc.RunCacheReconcile(ctx)
+	}()
+
+	// Создаем секции заказов наперед и отсоединяем старые
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		repo.RunPartitionMaintenance(ctx)
+	}()
+
+	// Пересчитываем агрегаты продаж для аналитики
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		repo.RunAnalyticsRefresh(ctx)
+	}()
+
+	// Периодически очищаем заказы старше срока хранения
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		svc.RunRetention(ctx)
+	}()
+
+	// Удаляем истекшие ключи идемпотентности HTTP API
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		svc.RunIdempotencyCleanup(ctx)
+	}()
+
 	// Запускаем HTTP сервер
 	zap.S().Infof("starting HTTP server on %s", cfg.ServerConfig.Port)
 	wg.Add(1)
@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"l0/internal/archive"
 	"l0/internal/broker"
 	"l0/internal/repository"
+	"l0/internal/service"
 	"l0/internal/transport/rest"
 	"l0/pkg/logger"
 
@@ -16,6 +18,7 @@ type Config struct {
 	LoggerConfig logger.Config
 	ServerConfig rest.Config
 	KafkaConfig  broker.Config
+	CacheConfig  service.Config
 }
 
 func NewConfig() (*Config, error) {
@@ -29,3 +32,36 @@ func NewConfig() (*Config, error) {
 
 	return &cfg, nil
 }
+
+// NewRepositoryConfig читает только настройки БД (для команд, которым не нужны Kafka и HTTP)
+func NewRepositoryConfig() (*repository.Config, error) {
+	godotenv.Load(".env")
+
+	cfg := repository.Config{}
+
+	if err := env.Parse(&cfg); err != nil {
+		return nil, fmt.Errorf("config error: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// ArchiveConfig - настройки команды l0-archive
+type ArchiveConfig struct {
+	DbConfig      repository.Config
+	ArchiveConfig archive.Config
+	BatchSize     int `env:"RETENTION_BATCH_SIZE" envDefault:"500"`
+}
+
+// NewArchiveConfig читает настройки БД и архива
+func NewArchiveConfig() (*ArchiveConfig, error) {
+	godotenv.Load(".env")
+
+	cfg := ArchiveConfig{}
+
+	if err := env.Parse(&cfg); err != nil {
+		return nil, fmt.Errorf("config error: %w", err)
+	}
+
+	return &cfg, nil
+}
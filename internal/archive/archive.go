@@ -0,0 +1,346 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const indexFile = "index.jsonl"
+
+type Config struct {
+	Dir string `env:"ARCHIVE_DIR"`
+}
+
+// Store - операции хранилища, нужные для переноса заказов в архив и обратно
+type Store interface {
+	ListExpiredOrders(ctx context.Context, before time.Time, deleted bool, limit int) ([]models.Order, error)
+	GetStatusHistories(ctx context.Context, orderUIDs []string) (map[string][]models.StatusChange, error)
+	GetRawOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.RawOrder, error)
+	PurgeOrders(ctx context.Context, orderUIDs []string) (int, error)
+	ImportOrder(ctx context.Context, order models.Order, history []models.StatusChange, raws []models.RawOrder) error
+}
+
+// Archive - каталог сжатых файлов JSON Lines с заказами, вынесенными из БД.
+// Индекс index.jsonl дописывается при каждой записи, поэтому изменения, сделанные
+// другим процессом (например, командой l0-archive), подхватываются при промахе.
+type Archive struct {
+	dir       string
+	mu        sync.RWMutex
+	index     map[string]IndexEntry // [order_uid]IndexEntry
+	indexSize int64                 // прочитанная часть index.jsonl
+}
+
+// Open открывает архив в каталоге dir, создавая каталог при необходимости
+func Open(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	a := &Archive{
+		dir:   dir,
+		index: make(map[string]IndexEntry),
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.loadIndex(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// loadIndex дочитывает новые записи индекса. Вызывается под блокировкой на запись.
+func (a *Archive) loadIndex() error {
+	f, err := os.Open(filepath.Join(a.dir, indexFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open archive index: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(a.indexSize, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read archive index: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("failed to read archive index: %w", err)
+	}
+	// Недописанную последнюю строку прочитаем в следующий раз
+	complete := bytes.LastIndexByte(data, '\n') + 1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data[:complete]))
+	for scanner.Scan() {
+		var entry IndexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("corrupted archive index: %w", err)
+		}
+		if entry.Removed {
+			delete(a.index, entry.OrderUID)
+			continue
+		}
+		a.index[entry.OrderUID] = entry
+	}
+	a.indexSize += int64(complete)
+	return scanner.Err()
+}
+
+// appendIndex дописывает записи в индекс. Вызывается под блокировкой на запись.
+func (a *Archive) appendIndex(entries []IndexEntry) error {
+	// Сначала подхватываем чужие записи, чтобы не пропустить их после сдвига indexSize
+	if err := a.loadIndex(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			return fmt.Errorf("failed to marshal archive index: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(a.dir, indexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive index: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write archive index: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync archive index: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close archive index: %w", err)
+	}
+
+	return a.loadIndex()
+}
+
+// Write атомарно записывает заказы в новый файл архива и добавляет их в индекс
+func (a *Archive) Write(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	name := fmt.Sprintf("orders-%s.jsonl.gz", time.Now().UTC().Format("20060102T150405.000000000"))
+	tmp, err := os.CreateTemp(a.dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	entries, err := WriteRecords(w, records)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	// Заказы удаляются из БД сразу после архивации, поэтому файл должен быть на диске
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync archive file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close archive file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(a.dir, name)); err != nil {
+		return fmt.Errorf("failed to rename archive file: %w", err)
+	}
+
+	for i := range entries {
+		entries[i].File = name
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.appendIndex(entries)
+}
+
+// lookup ищет заказ в индексе, при промахе перечитывая индекс
+func (a *Archive) lookup(orderUID string) (IndexEntry, bool, error) {
+	a.mu.RLock()
+	entry, ok := a.index[orderUID]
+	a.mu.RUnlock()
+	if ok {
+		return entry, true, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.loadIndex(); err != nil {
+		return IndexEntry{}, false, err
+	}
+	entry, ok = a.index[orderUID]
+	return entry, ok, nil
+}
+
+// Get читает заказ из архива
+func (a *Archive) Get(orderUID string) (models.Order, error) {
+	record, err := a.GetRecord(orderUID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	return record.Order, nil
+}
+
+// GetRecord читает заказ из архива вместе с историей статусов и исходными сообщениями
+func (a *Archive) GetRecord(orderUID string) (Record, error) {
+	entry, ok, err := a.lookup(orderUID)
+	if err != nil {
+		return Record{}, err
+	}
+	if !ok {
+		return Record{}, fmt.Errorf("archived order retrieval error: %w", er.ErrOrderNotFound)
+	}
+
+	f, err := os.Open(filepath.Join(a.dir, entry.File))
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to open archive file: %w", err)
+	}
+	defer f.Close()
+	return ReadRecord(io.NewSectionReader(f, entry.Offset, entry.Length))
+}
+
+// archived возвращает записи индекса для заказов, уже находящихся в архиве
+func (a *Archive) archived(orderUIDs []string) (map[string]IndexEntry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.loadIndex(); err != nil {
+		return nil, err
+	}
+	entries := make(map[string]IndexEntry)
+	for _, orderUID := range orderUIDs {
+		if entry, ok := a.index[orderUID]; ok {
+			entries[orderUID] = entry
+		}
+	}
+	return entries, nil
+}
+
+// Remove исключает заказы из индекса архива. Файлы архива не меняются.
+func (a *Archive) Remove(orderUIDs ...string) error {
+	entries := make([]IndexEntry, len(orderUIDs))
+	for i, uid := range orderUIDs {
+		entries[i] = IndexEntry{OrderUID: uid, Removed: true}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.appendIndex(entries)
+}
+
+// Len возвращает число заказов в архиве
+func (a *Archive) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.index)
+}
+
+// MoveOrders переносит заказы, созданные раньше before, из хранилища в архив пачками по batchSize
+// вместе с историей статусов и исходными сообщениями. Мягко удаленные заказы не переносятся.
+// moved вызывается с ID каждой перенесенной пачки.
+//
+// Заказ сначала записывается в архив и только потом удаляется из хранилища. Если удаление
+// не удалось, повторный запуск не записывает заказ в архив второй раз, если его версия не изменилась.
+func (a *Archive) MoveOrders(ctx context.Context, store Store, before time.Time, batchSize int, moved func(orderUIDs []string)) (int, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("%w: batch size must be greater than zero", er.ErrInvalidData)
+	}
+
+	var total int
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		orders, err := store.ListExpiredOrders(ctx, before, false, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(orders) == 0 {
+			return total, nil
+		}
+
+		uids := make([]string, len(orders))
+		for i := range orders {
+			uids[i] = orders[i].OrderUID
+		}
+		records, err := a.newRecords(ctx, store, orders, uids)
+		if err != nil {
+			return total, err
+		}
+		if err := a.Write(records); err != nil {
+			return total, err
+		}
+		purged, err := store.PurgeOrders(ctx, uids)
+		if err != nil {
+			return total, err
+		}
+		if moved != nil {
+			moved(uids)
+		}
+		total += purged
+
+		if len(orders) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// newRecords собирает записи архива для заказов, которых еще нет в архиве в той же версии
+func (a *Archive) newRecords(ctx context.Context, store Store, orders []models.Order, uids []string) ([]Record, error) {
+	archived, err := a.archived(uids)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := store.GetStatusHistories(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	raws, err := store.GetRawOrdersByUIDs(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(orders))
+	for _, order := range orders {
+		if entry, ok := archived[order.OrderUID]; ok && entry.Version == order.Version {
+			continue
+		}
+		records = append(records, Record{
+			Order:         order,
+			StatusHistory: histories[order.OrderUID],
+			RawOrders:     raws[order.OrderUID],
+		})
+	}
+	return records, nil
+}
+
+// RestoreOrder возвращает заказ из архива в хранилище вместе с историей статусов
+// и исходными сообщениями и исключает его из индекса
+func (a *Archive) RestoreOrder(ctx context.Context, store Store, orderUID string) (models.Order, error) {
+	record, err := a.GetRecord(orderUID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if err := store.ImportOrder(ctx, record.Order, record.StatusHistory, record.RawOrders); err != nil {
+		return models.Order{}, err
+	}
+	if err := a.Remove(orderUID); err != nil {
+		return models.Order{}, err
+	}
+	return record.Order, nil
+}
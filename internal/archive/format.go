@@ -0,0 +1,109 @@
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"l0/internal/models"
+	"time"
+)
+
+// IndexEntry - положение заказа в файле архива.
+// Каждый заказ - отдельный gzip-член, поэтому его можно распаковать по Offset и Length,
+// не читая файл целиком, а весь файл при этом остается обычным .jsonl.gz.
+type IndexEntry struct {
+	OrderUID    string    `json:"order_uid"`
+	File        string    `json:"file,omitempty"`
+	Offset      int64     `json:"offset,omitempty"`
+	Length      int64     `json:"length,omitempty"`
+	DateCreated time.Time `json:"date_created"`
+	Version     int64     `json:"version,omitempty"` // версия заказа в БД на момент архивации
+	Removed     bool      `json:"removed,omitempty"` // заказ восстановлен из архива
+}
+
+// Record - строка архива: заказ в формате Kafka с историей статусов и исходными сообщениями,
+// которые нужны, чтобы вернуть заказ в БД без потерь
+type Record struct {
+	models.Order
+	StatusHistory []models.StatusChange `json:"status_history,omitempty"`
+	RawOrders     []models.RawOrder     `json:"raw_orders,omitempty"`
+	// MinorUnits - суммы в минимальных единицах валюты. В записях, архивированных
+	// до миграции 11, суммы в основных единицах и пересчитываются при чтении.
+	MinorUnits bool `json:"minor_units,omitempty"`
+}
+
+// toMinorUnits пересчитывает суммы записи из основных единиц валюты в минимальные.
+// Исходные сообщения таких записей тоже в основных единицах и отмечаются MajorUnits.
+func (r *Record) toMinorUnits() {
+	r.Order.ToMinorUnits()
+	for i := range r.RawOrders {
+		r.RawOrders[i].MajorUnits = true
+	}
+	r.MinorUnits = true
+}
+
+// countingWriter считает записанные байты
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteRecords записывает записи архива в w в формате JSON Lines, сжимая каждую строку отдельным gzip-членом.
+// Возвращает положение каждого заказа относительно начала записи.
+func WriteRecords(w io.Writer, records []Record) ([]IndexEntry, error) {
+	cw := &countingWriter{w: w}
+	entries := make([]IndexEntry, 0, len(records))
+	for i := range records {
+		record := records[i]
+		record.MinorUnits = true
+		line, err := json.Marshal(&record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal order %s: %w", records[i].OrderUID, err)
+		}
+
+		start := cw.n
+		zw := gzip.NewWriter(cw)
+		if _, err := zw.Write(append(line, '\n')); err != nil {
+			return nil, fmt.Errorf("failed to compress order %s: %w", records[i].OrderUID, err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress order %s: %w", records[i].OrderUID, err)
+		}
+
+		entries = append(entries, IndexEntry{
+			OrderUID:    records[i].OrderUID,
+			Offset:      start,
+			Length:      cw.n - start,
+			DateCreated: records[i].DateCreated,
+			Version:     records[i].Version,
+		})
+	}
+	return entries, nil
+}
+
+// ReadRecord распаковывает одну запись, записанную WriteRecords
+func ReadRecord(r io.Reader) (Record, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to decompress archived order: %w", err)
+	}
+	defer zr.Close()
+	// Читаем только один gzip-член
+	zr.Multistream(false)
+
+	var record Record
+	if err := json.NewDecoder(zr).Decode(&record); err != nil {
+		return Record{}, fmt.Errorf("failed to unmarshal archived order: %w", err)
+	}
+	if !record.MinorUnits {
+		record.toMinorUnits()
+	}
+	return record, nil
+}
@@ -28,8 +28,8 @@ func NewConsumer(config Config) (*Consumer, error) {
 	return &Consumer{reader: reader, topic: config.Topic}, nil
 }
 
-// ConsumeOrders читает заказы из Kafka и передает их в handler
-func (c *Consumer) ConsumeOrders(ctx context.Context, handler func(*models.Order) error) error {
+// ConsumeOrders читает заказы из Kafka и передает их в handler вместе с исходным сообщением
+func (c *Consumer) ConsumeOrders(ctx context.Context, handler func(*models.Order, models.RawOrder) error) error {
 	zap.S().Infof("starting to consume orders from topic: %s", c.topic)
 
 	for {
@@ -61,7 +61,15 @@ func (c *Consumer) ConsumeOrders(ctx context.Context, handler func(*models.Order
 				continue
 			}
 
-			if err := handler(&order); err != nil {
+			raw := models.RawOrder{
+				OrderUID:   order.OrderUID,
+				Payload:    msg.Value,
+				Topic:      msg.Topic,
+				Partition:  msg.Partition,
+				Offset:     msg.Offset,
+				IngestedAt: time.Now(),
+			}
+			if err := handler(&order, raw); err != nil {
 				zap.S().Warnf("handler error: %v", err)
 				continue
 			}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"l0/pkg/er"
+	"time"
+)
+
+// SalesDimension - разрез, по которому строятся временные ряды продаж
+type SalesDimension string
+
+const (
+	SalesTotal             SalesDimension = "total" // все заказы одним рядом на валюту
+	SalesByBrand           SalesDimension = "brand"
+	SalesByDeliveryService SalesDimension = "delivery_service"
+	SalesByProvider        SalesDimension = "provider"
+	SalesByRegion          SalesDimension = "region"
+)
+
+// Valid сообщает, что разрез поддерживается
+func (d SalesDimension) Valid() bool {
+	switch d {
+	case SalesTotal, SalesByBrand, SalesByDeliveryService, SalesByProvider, SalesByRegion:
+		return true
+	}
+	return false
+}
+
+// SalesInterval - шаг временного ряда
+type SalesInterval string
+
+const (
+	SalesDaily  SalesInterval = "day"
+	SalesWeekly SalesInterval = "week" // недели начинаются с понедельника
+)
+
+// Valid сообщает, что шаг поддерживается
+func (i SalesInterval) Valid() bool {
+	return i == SalesDaily || i == SalesWeekly
+}
+
+// Truncate возвращает начало периода, в который попадает t (в UTC)
+func (i SalesInterval) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if i == SalesWeekly {
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+// SalesMaxRange - наибольший период, за который можно запросить аналитику
+const SalesMaxRange = 2 * 366 * 24 * time.Hour
+
+// SalesQuery - параметры запроса аналитики продаж.
+// Учитываются заказы, созданные в [From, To); границы округляются до суток.
+type SalesQuery struct {
+	Dimension SalesDimension
+	Interval  SalesInterval
+	From      time.Time
+	To        time.Time
+}
+
+// RoundToDays округляет границы запроса до суток: From вниз, To вверх
+func (q *SalesQuery) RoundToDays() {
+	to := SalesDaily.Truncate(q.To)
+	if !to.Equal(q.To) {
+		to = to.AddDate(0, 0, 1)
+	}
+	q.From, q.To = SalesDaily.Truncate(q.From), to
+}
+
+// Next возвращает начало периода, следующего за period
+func (i SalesInterval) Next(period time.Time) time.Time {
+	if i == SalesWeekly {
+		return period.AddDate(0, 0, 7)
+	}
+	return period.AddDate(0, 0, 1)
+}
+
+// Validate проверяет параметры запроса
+func (q *SalesQuery) Validate() error {
+	if !q.Dimension.Valid() {
+		return fmt.Errorf("%w: unknown analytics dimension %q", er.ErrInvalidData, q.Dimension)
+	}
+	if !q.Interval.Valid() {
+		return fmt.Errorf("%w: interval must be %q or %q", er.ErrInvalidData, SalesDaily, SalesWeekly)
+	}
+	if !q.From.Before(q.To) {
+		return fmt.Errorf("%w: from must be before to", er.ErrInvalidData)
+	}
+	if q.To.Sub(q.From) > SalesMaxRange {
+		return fmt.Errorf("%w: analytics range must not exceed %d days", er.ErrInvalidData, int(SalesMaxRange.Hours()/24))
+	}
+	return nil
+}
+
+// SalesRow - агрегат продаж за период по одному значению разреза в одной валюте
+type SalesRow struct {
+	Period       time.Time
+	Key          string
+	Currency     Currency
+	Orders       int64
+	Revenue      int64 // в минимальных единицах Currency
+	DeliveryCost int64 // в минимальных единицах Currency, для брендов всегда 0
+}
+
+// SalesPoint - точка временного ряда продаж. Суммы в минимальных единицах валюты ряда.
+type SalesPoint struct {
+	Period        time.Time `json:"period"`
+	Orders        int64     `json:"orders"`
+	Revenue       int64     `json:"revenue"`
+	AverageBasket int64     `json:"average_basket"`
+	DeliveryCost  int64     `json:"delivery_cost"`
+}
+
+// SalesSeries - временной ряд продаж по значению разреза. Суммы в разных валютах
+// не складываются, поэтому у каждого значения свой ряд на каждую валюту.
+type SalesSeries struct {
+	Key      string       `json:"key"`
+	Currency Currency     `json:"currency"`
+	Points   []SalesPoint `json:"points"`
+}
+
+// SalesReport - ответ аналитики продаж
+type SalesReport struct {
+	Dimension SalesDimension `json:"dimension"`
+	Interval  SalesInterval  `json:"interval"`
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Series    []SalesSeries  `json:"series"`
+}
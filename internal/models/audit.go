@@ -0,0 +1,163 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditAction - вид изменения заказа в журнале аудита
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"  // заказ создан
+	AuditUpdate  AuditAction = "update"  // данные заказа перезаписаны
+	AuditStatus  AuditAction = "status"  // статус изменен
+	AuditDelete  AuditAction = "delete"  // заказ мягко удален
+	AuditRestore AuditAction = "restore" // мягкое удаление отменено
+	AuditPurge   AuditAction = "purge"   // заказ удален безвозвратно
+	// AuditArchiveRestore - заказ возвращен из архива. Before содержит версию заказа в архиве,
+	// After - все поля, как при создании.
+	AuditArchiveRestore AuditAction = "archive_restore"
+)
+
+// Виды источников изменений
+const (
+	AuditSourceKafka  = "kafka"
+	AuditSourceAdmin  = "admin"
+	AuditSourceSystem = "system"
+	AuditSourceAPI    = "api" // HTTP API приема заказов
+)
+
+// AuditSource - кто или что изменило заказ
+type AuditSource struct {
+	Kind string `json:"kind"`
+	// Actor - topic/partition/offset сообщения Kafka, пользователь API или фоновая задача
+	Actor string `json:"actor,omitempty"`
+}
+
+// AuditEntry - запись журнала аудита заказа. Before и After содержат измененные поля
+// до и после изменения: все поля заказа при создании, только измененные в остальных случаях.
+// Вложенные поля записываются через точку ("payment.amount"), персональные данные - AuditRedacted.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	OrderUID  string          `json:"order_uid"`
+	Action    AuditAction     `json:"action"`
+	Source    AuditSource     `json:"source"`
+	Version   int64           `json:"version"` // версия заказа после изменения
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+type auditSourceKey struct{}
+
+// WithAuditSource сохраняет в контексте источник изменений, который хранилище запишет в журнал аудита
+func WithAuditSource(ctx context.Context, source AuditSource) context.Context {
+	return context.WithValue(ctx, auditSourceKey{}, source)
+}
+
+// AuditSourceFromContext возвращает источник изменений из контекста, по умолчанию - system
+func AuditSourceFromContext(ctx context.Context) AuditSource {
+	if source, ok := ctx.Value(auditSourceKey{}).(AuditSource); ok {
+		return source
+	}
+	return AuditSource{Kind: AuditSourceSystem}
+}
+
+// Source возвращает источник изменений для заказа из этого сообщения
+func (r RawOrder) Source() AuditSource {
+	return AuditSource{
+		Kind:  AuditSourceKafka,
+		Actor: r.Topic + "/" + strconv.Itoa(r.Partition) + "/" + strconv.FormatInt(r.Offset, 10),
+	}
+}
+
+// AuditRedacted заменяет в журнале аудита значения персональных и платежных данных.
+// Журнал хранится бессрочно и переживает очистку заказа, поэтому сами значения в него не пишутся.
+const AuditRedacted = "[redacted]"
+
+// auditRedacted сообщает, скрывается ли поле path в журнале аудита
+func auditRedacted(path string) bool {
+	switch path {
+	case "customer_id", "payment.transaction", "payment.request_id":
+		return true
+	}
+	return strings.HasPrefix(path, "delivery.")
+}
+
+// AuditDiff возвращает поля заказа до и после изменения для журнала аудита.
+// Без before (создание) в after попадают все поля заказа, иначе - только измененные.
+func AuditDiff(before, after *Order) (map[string]any, map[string]any, error) {
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if before == nil {
+		for path := range afterFields {
+			if auditRedacted(path) {
+				afterFields[path] = AuditRedacted
+			}
+		}
+		return nil, afterFields, nil
+	}
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changedBefore, changedAfter := map[string]any{}, map[string]any{}
+	for path, value := range afterFields {
+		old, ok := beforeFields[path]
+		if ok && reflect.DeepEqual(old, value) {
+			continue
+		}
+		if auditRedacted(path) {
+			old, value = AuditRedacted, AuditRedacted
+		}
+		changedBefore[path], changedAfter[path] = old, value
+	}
+	// Поля с omitempty могли пропасть из нового заказа
+	for path, old := range beforeFields {
+		if _, ok := afterFields[path]; ok {
+			continue
+		}
+		if auditRedacted(path) {
+			old = AuditRedacted
+		}
+		changedBefore[path], changedAfter[path] = old, nil
+	}
+	return changedBefore, changedAfter, nil
+}
+
+// auditFields разворачивает JSON заказа в поля "путь": значение. Списки остаются значениями,
+// версия заказа не включается - она хранится в самой записи журнала.
+func auditFields(order *Order) (map[string]any, error) {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("audit record marshaling error: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("audit record marshaling error: %w", err)
+	}
+	delete(fields, "version")
+
+	flat := make(map[string]any, len(fields))
+	var flatten func(prefix string, fields map[string]any)
+	flatten = func(prefix string, fields map[string]any) {
+		for key, value := range fields {
+			if nested, ok := value.(map[string]any); ok {
+				flatten(prefix+key+".", nested)
+				continue
+			}
+			flat[prefix+key] = value
+		}
+	}
+	flatten("", fields)
+	return flat, nil
+}
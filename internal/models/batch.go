@@ -0,0 +1,16 @@
+package models
+
+import "encoding/json"
+
+// Статусы результата пакетного получения заказов
+const (
+	BatchOrderFound    = "ok"
+	BatchOrderNotFound = "not_found"
+)
+
+// BatchOrderResult - результат получения одного заказа из пакета
+type BatchOrderResult struct {
+	OrderUID string          `json:"order_uid"`
+	Status   string          `json:"status"`
+	Order    json.RawMessage `json:"order,omitempty"` // OrderResponse, если заказ найден
+}
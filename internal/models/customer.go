@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// CustomerSpent - сумма заказов покупателя в одной валюте
+type CustomerSpent struct {
+	Currency        Currency `json:"currency"`
+	Amount          int64    `json:"amount"`
+	AmountFormatted string   `json:"amount_formatted"`
+}
+
+// BrandStat - сколько заказов и товаров бренда купил покупатель
+type BrandStat struct {
+	Brand  string `json:"brand"`
+	Orders int64  `json:"orders"`
+	Items  int64  `json:"items"`
+}
+
+// CustomerSummary - сводка по заказам покупателя. Удаленные заказы не учитываются,
+// отмененные и возвращенные не входят в TotalSpent.
+type CustomerSummary struct {
+	CustomerID     string          `json:"customer_id"`
+	OrderCount     int64           `json:"order_count"`
+	TotalSpent     []CustomerSpent `json:"total_spent"` // по валютам
+	FirstOrderAt   time.Time       `json:"first_order_at"`
+	LastOrderAt    time.Time       `json:"last_order_at"`
+	FavoriteBrands []BrandStat     `json:"favorite_brands"`
+}
+
+// CustomerOrdersResponse - страница заказов покупателя. Сводка возвращается только с первой страницей.
+type CustomerOrdersResponse struct {
+	Summary    *CustomerSummary `json:"summary,omitempty"`
+	Items      []OrderResponse  `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// IdempotencyKey - запрос с заголовком Idempotency-Key и сохраненный ответ на него.
+// Повтор запроса с тем же ключом получает сохраненный ответ без повторной обработки.
+type IdempotencyKey struct {
+	Client      string // пользователь API, ключи разных клиентов не пересекаются
+	Key         string
+	RequestHash string // SHA-256 тела запроса
+	StatusCode  int    // 0 - запрос еще обрабатывается
+	Response    []byte
+	CreatedAt   time.Time
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"l0/pkg/er"
+	"strings"
+	"time"
+)
+
+// OrderFilter - условия отбора заказов для постраничного списка.
+// Пустые поля не участвуют в отборе.
+type OrderFilter struct {
+	TrackNumber     string
+	CustomerID      string
+	Phone           string
+	Email           string // сравнивается без учета регистра
+	DeliveryService string
+	DateFrom        time.Time // date_created >= DateFrom
+	DateTo          time.Time // date_created < DateTo
+	PaymentProvider string
+	Bank            string
+	// Условия по товарам: в заказе должен быть товар, подходящий под все заданные поля
+	Brand  string
+	NmID   int
+	ChrtID int
+}
+
+// IsEmpty сообщает, что фильтр не задает ни одного условия
+func (f OrderFilter) IsEmpty() bool {
+	return f == OrderFilter{}
+}
+
+// HasItemConditions сообщает, что фильтр содержит условия по товарам
+func (f OrderFilter) HasItemConditions() bool {
+	return f.Brand != "" || f.NmID != 0 || f.ChrtID != 0
+}
+
+// Cursor - позиция в списке заказов, упорядоченном по (date_created, order_uid)
+type Cursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+// Encode возвращает непрозрачное строковое представление курсора
+func (c Cursor) Encode() string {
+	raw := c.DateCreated.UTC().Format(time.RFC3339Nano) + "|" + c.OrderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor разбирает курсор, полученный от Encode
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed cursor", er.ErrInvalidData)
+	}
+	date, orderUID, ok := strings.Cut(string(raw), "|")
+	if !ok || orderUID == "" {
+		return Cursor{}, fmt.Errorf("%w: malformed cursor", er.ErrInvalidData)
+	}
+	dateCreated, err := time.Parse(time.RFC3339Nano, date)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed cursor", er.ErrInvalidData)
+	}
+	return Cursor{DateCreated: dateCreated, OrderUID: orderUID}, nil
+}
+
+// OrderPage - страница заказов и курсор следующей страницы (пустой, если это последняя)
+type OrderPage struct {
+	Orders     []Order
+	NextCursor string
+}
+
+// OrderListResponse - страница заказов для отображения пользователю
+type OrderListResponse struct {
+	Items      []OrderResponse `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
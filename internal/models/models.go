@@ -17,6 +17,19 @@ type Order struct {
 	SmID              int       `json:"sm_id"`
 	DateCreated       time.Time `json:"date_created"`
 	OofShard          string    `json:"oof_shard"`
+	Status            Status    `json:"status,omitempty"`
+	// Version - версия заказа в БД, увеличивается при каждом изменении
+	Version int64 `json:"version,omitempty"`
+	// EventVersion - версия события источника. Событие Kafka с версией не новее
+	// уже примененной игнорируется.
+	EventVersion int64 `json:"event_version,omitempty"`
+}
+
+// OrderChanges - изменения заказов после метки времени
+type OrderChanges struct {
+	Versions  map[string]int64 // [order_uid]версия созданных и измененных активных заказов
+	Removed   []string         // мягко или безвозвратно удаленные заказы
+	Watermark time.Time        // время БД на начало чтения, метка для следующего запроса
 }
 
 // OrderResponse - структура для безопасного отображения заказа пользователю
@@ -29,6 +42,10 @@ type OrderResponse struct {
 	Locale          string          `json:"locale"`
 	DeliveryService string          `json:"delivery_service"`
 	DateCreated     time.Time       `json:"date_created"`
+	Status          string          `json:"status"`
+	Version         int64           `json:"version"`
+	// Converted - сумма платежа в других валютах, только по запросу с ?currency=
+	Converted []ConvertedAmount `json:"converted,omitempty"`
 }
 
 type Delivery struct {
@@ -41,28 +58,36 @@ type Delivery struct {
 	Email   string `json:"email"`
 }
 
+// Payment - платеж заказа. Суммы платежа и товаров заказа указываются
+// в минимальных единицах валюты Currency (центах, копейках).
 type Payment struct {
-	Transaction  string `json:"transaction"`
-	RequestID    string `json:"request_id"`
-	Currency     string `json:"currency"`
-	Provider     string `json:"provider"`
-	Amount       int    `json:"amount"`
-	PaymentDt    int    `json:"payment_dt"`
-	Bank         string `json:"bank"`
-	DeliveryCost int    `json:"delivery_cost"`
-	GoodsTotal   int    `json:"goods_total"`
-	CustomFee    int    `json:"custom_fee"`
+	Transaction  string   `json:"transaction"`
+	RequestID    string   `json:"request_id"`
+	Currency     Currency `json:"currency"`
+	Provider     string   `json:"provider"`
+	Amount       int64    `json:"amount"`
+	PaymentDt    int      `json:"payment_dt"`
+	Bank         string   `json:"bank"`
+	DeliveryCost int64    `json:"delivery_cost"`
+	GoodsTotal   int64    `json:"goods_total"`
+	CustomFee    int64    `json:"custom_fee"`
 }
 
+// PaymentResponse - платеж для отображения пользователю. Поля *_formatted содержат
+// суммы в основных единицах валюты, например "1817.00".
 type PaymentResponse struct {
-	Currency     string `json:"currency"`
-	Provider     string `json:"provider"`
-	Amount       int    `json:"amount"`
-	PaymentDt    int    `json:"payment_dt"`
-	Bank         string `json:"bank"`
-	DeliveryCost int    `json:"delivery_cost"`
-	GoodsTotal   int    `json:"goods_total"`
-	CustomFee    int    `json:"custom_fee"`
+	Currency              Currency `json:"currency"`
+	Provider              string   `json:"provider"`
+	Amount                int64    `json:"amount"`
+	AmountFormatted       string   `json:"amount_formatted"`
+	PaymentDt             int      `json:"payment_dt"`
+	Bank                  string   `json:"bank"`
+	DeliveryCost          int64    `json:"delivery_cost"`
+	DeliveryCostFormatted string   `json:"delivery_cost_formatted"`
+	GoodsTotal            int64    `json:"goods_total"`
+	GoodsTotalFormatted   string   `json:"goods_total_formatted"`
+	CustomFee             int64    `json:"custom_fee"`
+	CustomFeeFormatted    string   `json:"custom_fee_formatted"`
 }
 
 type Items []Item
@@ -70,25 +95,27 @@ type Items []Item
 type Item struct {
 	ChrtID      int    `json:"chrt_id"`
 	TrackNumber string `json:"track_number"`
-	Price       int    `json:"price"`
+	Price       int64  `json:"price"`
 	Rid         string `json:"rid"`
 	Name        string `json:"name"`
 	Sale        int    `json:"sale"`
 	Size        string `json:"size"`
-	TotalPrice  int    `json:"total_price"`
+	TotalPrice  int64  `json:"total_price"`
 	NmID        int    `json:"nm_id"`
 	Brand       string `json:"brand"`
-	Status      int    `json:"status"`
+	Status      Status `json:"status"`
 }
 
 type ItemResponse struct {
-	TrackNumber string `json:"track_number"`
-	Price       int    `json:"price"`
-	Name        string `json:"name"`
-	Sale        int    `json:"sale"`
-	Size        string `json:"size"`
-	TotalPrice  int    `json:"total_price"`
-	Brand       string `json:"brand"`
+	TrackNumber         string `json:"track_number"`
+	Price               int64  `json:"price"`
+	PriceFormatted      string `json:"price_formatted"`
+	Name                string `json:"name"`
+	Sale                int    `json:"sale"`
+	Size                string `json:"size"`
+	TotalPrice          int64  `json:"total_price"`
+	TotalPriceFormatted string `json:"total_price_formatted"`
+	Brand               string `json:"brand"`
 }
 
 type ItemsResponse []ItemResponse
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"l0/pkg/er"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RateBaseCurrency - валюта, в которой задаются курсы. Ее курс всегда 1.
+const RateBaseCurrency Currency = "RUB"
+
+// rateFormat - курс в виде обычной десятичной дроби, которая без округления помещается в NUMERIC(24, 10).
+// big.Rat принимает и "1/3", и "1e5", но Postgres такие строки не разбирает, а лишние знаки округляет.
+var rateFormat = regexp.MustCompile(`^[0-9]{1,14}(\.[0-9]{1,10})?$`)
+
+// ExchangeRate - курс валюты на дату: цена одной основной единицы Currency в RateBaseCurrency.
+// Курс действует с Date до следующей даты, на которую загружен курс этой валюты.
+type ExchangeRate struct {
+	Date     time.Time `json:"date"`
+	Currency Currency  `json:"currency"`
+	Rate     string    `json:"rate"` // десятичная строка, например "89.6883"
+}
+
+// Validate проверяет курс перед сохранением
+func (r *ExchangeRate) Validate() error {
+	if r.Date.IsZero() {
+		return fmt.Errorf("%w: exchange rate date is required", er.ErrInvalidData)
+	}
+	if !r.Currency.Valid() {
+		return fmt.Errorf("%w: unknown currency %q, expected ISO 4217 code", er.ErrInvalidData, r.Currency)
+	}
+	if !rateFormat.MatchString(r.Rate) {
+		return fmt.Errorf("%w: exchange rate of %s must be a decimal with at most 14 integer and 10 fractional digits, got %q",
+			er.ErrInvalidData, r.Currency, r.Rate)
+	}
+	rate, ok := r.Value()
+	if !ok || rate.Sign() <= 0 {
+		return fmt.Errorf("%w: exchange rate of %s must be a positive decimal, got %q", er.ErrInvalidData, r.Currency, r.Rate)
+	}
+	if r.Currency == RateBaseCurrency && rate.Cmp(big.NewRat(1, 1)) != 0 {
+		return fmt.Errorf("%w: rate of base currency %s is always 1", er.ErrInvalidData, RateBaseCurrency)
+	}
+	return nil
+}
+
+// Value возвращает курс как точную дробь
+func (r *ExchangeRate) Value() (*big.Rat, bool) {
+	return new(big.Rat).SetString(r.Rate)
+}
+
+// ParseExchangeRatesCSV читает курсы в формате CSV: date,currency,rate (дата - YYYY-MM-DD).
+// Строка заголовка необязательна, пустые строки пропускаются.
+func ParseExchangeRatesCSV(r io.Reader) ([]ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var rates []ExchangeRate
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: rates CSV: %v", er.ErrInvalidData, err)
+		}
+		if line == 1 && strings.EqualFold(record[0], "date") {
+			continue
+		}
+
+		date, err := time.Parse(time.DateOnly, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: rates CSV line %d: date must be YYYY-MM-DD", er.ErrInvalidData, line)
+		}
+		rate := ExchangeRate{Date: date, Currency: Currency(strings.ToUpper(record[1])), Rate: record[2]}
+		if err := rate.Validate(); err != nil {
+			return nil, fmt.Errorf("rates CSV line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+}
+
+// ConvertedAmount - сумма платежа заказа в другой валюте по курсу на дату платежа
+type ConvertedAmount struct {
+	Currency        Currency  `json:"currency"`
+	Amount          int64     `json:"amount"`
+	AmountFormatted string    `json:"amount_formatted"`
+	Rate            string    `json:"rate"`      // кросс-курс: цена единицы валюты платежа в Currency
+	RateDate        time.Time `json:"rate_date"` // дата, с которой действует примененный кросс-курс
+}
+
+// Convert переводит сумму в минимальных единицах валюты c в валюту to по курсам исходной и целевой
+// валюты к RateBaseCurrency. Результат в минимальных единицах to, половина округляется от нуля.
+func (c Currency) Convert(amount int64, to Currency, fromRate, toRate *big.Rat) (int64, error) {
+	value := new(big.Rat).SetFrac(big.NewInt(amount), pow10(c.Exponent()))
+	value.Mul(value, fromRate)
+	value.Quo(value, toRate)
+	value.Mul(value, new(big.Rat).SetInt(pow10(to.Exponent())))
+
+	// Округление: |value| + 1/2, затем целая часть со знаком
+	num := new(big.Int).Abs(value.Num())
+	num.Mul(num, big.NewInt(2))
+	num.Add(num, value.Denom())
+	den := new(big.Int).Mul(value.Denom(), big.NewInt(2))
+	converted := num.Quo(num, den)
+	if value.Sign() < 0 {
+		converted.Neg(converted)
+	}
+	if !converted.IsInt64() {
+		return 0, fmt.Errorf("%w: converted amount overflows", er.ErrInvalidData)
+	}
+	return converted.Int64(), nil
+}
+
+func pow10(exp int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RawTopicHTTP - значение Topic для заказов, принятых через HTTP API, а не из Kafka
+const RawTopicHTTP = "http"
+
+// RawOrder - исходное сообщение, из которого был получен заказ
+type RawOrder struct {
+	OrderUID   string          `json:"order_uid"`
+	Payload    json.RawMessage `json:"payload"`
+	Topic      string          `json:"topic"`
+	Partition  int             `json:"partition"`
+	Offset     int64           `json:"offset"`
+	IngestedAt time.Time       `json:"ingested_at"`
+	// MajorUnits - суммы в Payload в основных единицах валюты: сообщение принято
+	// до перехода на минимальные единицы и пересчитывается при повторной нормализации
+	MajorUnits bool `json:"major_units,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"html"
+	"strings"
+)
+
+// Маркеры начала и конца подсветки в сниппетах хранилища.
+// Управляющие символы не встречаются в данных заказов, поэтому сниппет можно
+// безопасно экранировать и только затем превратить маркеры в HTML.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+// SearchHit - результат полнотекстового поиска в хранилище
+type SearchHit struct {
+	OrderUID string
+	Rank     float64
+	Snippet  string // текст с маркерами HighlightStart/HighlightStop
+}
+
+// FullTextResult - найденный заказ с рангом и подсвеченным фрагментом
+type FullTextResult struct {
+	Order   OrderResponse `json:"order"`
+	Rank    float64       `json:"rank"`
+	Snippet string        `json:"snippet"` // HTML, совпадения обернуты в <mark>
+}
+
+// HighlightHTML экранирует сниппет и заменяет маркеры подсветки на <mark>
+func HighlightHTML(snippet string) string {
+	return strings.NewReplacer(
+		HighlightStart, "<mark>",
+		HighlightStop, "</mark>",
+	).Replace(html.EscapeString(snippet))
+}
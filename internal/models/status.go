@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"l0/pkg/er"
+	"strconv"
+	"time"
+)
+
+// Status - статус заказа и его товаров
+type Status int
+
+const (
+	StatusCreated    Status = 100 // заказ получен
+	StatusAccepted   Status = 202 // принят в обработку
+	StatusAssembling Status = 300 // собирается на складе
+	StatusShipped    Status = 400 // передан в доставку
+	StatusDelivered  Status = 500 // доставлен покупателю
+	StatusCancelled  Status = 900 // отменен
+	StatusReturned   Status = 910 // возвращен покупателем
+)
+
+var statusNames = map[Status]string{
+	StatusCreated:    "created",
+	StatusAccepted:   "accepted",
+	StatusAssembling: "assembling",
+	StatusShipped:    "shipped",
+	StatusDelivered:  "delivered",
+	StatusCancelled:  "cancelled",
+	StatusReturned:   "returned",
+}
+
+// statusTransitions - разрешенные переходы между статусами
+var statusTransitions = map[Status][]Status{
+	StatusCreated:    {StatusAccepted, StatusCancelled},
+	StatusAccepted:   {StatusAssembling, StatusCancelled},
+	StatusAssembling: {StatusShipped, StatusCancelled},
+	StatusShipped:    {StatusDelivered, StatusReturned},
+	StatusDelivered:  {StatusReturned},
+}
+
+// String возвращает имя статуса
+func (s Status) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return "unknown(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Valid сообщает, что статус известен
+func (s Status) Valid() bool {
+	_, ok := statusNames[s]
+	return ok
+}
+
+// CanTransitionTo сообщает, разрешен ли переход в статус to
+func (s Status) CanTransitionTo(to Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeStatus задает статус нового заказа, если он не указан, и переносит статус заказа на товары.
+// Без статуса заказ получает общий статус своих товаров, а если он не определен - StatusCreated.
+func (o *Order) NormalizeStatus() {
+	if o.Status == 0 {
+		o.Status = StatusCreated
+		if len(o.Items) > 0 && o.Items[0].Status.Valid() {
+			o.Status = o.Items[0].Status
+		}
+		for _, item := range o.Items {
+			if item.Status != o.Status {
+				o.Status = StatusCreated
+				break
+			}
+		}
+	}
+	for i := range o.Items {
+		o.Items[i].Status = o.Status
+	}
+}
+
+// ParseStatus разбирает статус по имени или числовому коду
+func ParseStatus(v string) (Status, error) {
+	for status, name := range statusNames {
+		if name == v {
+			return status, nil
+		}
+	}
+	if code, err := strconv.Atoi(v); err == nil && Status(code).Valid() {
+		return Status(code), nil
+	}
+	return 0, fmt.Errorf("%w: unknown status %q", er.ErrInvalidData, v)
+}
+
+// StatusChange - запись истории статусов заказа
+type StatusChange struct {
+	OrderUID  string    `json:"order_uid"`
+	From      Status    `json:"from,omitempty"` // 0 для первой записи
+	To        Status    `json:"to"`
+	Comment   string    `json:"comment,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// StatusChangeResponse - запись истории статусов для отображения пользователю
+type StatusChangeResponse struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	Comment   string    `json:"comment,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
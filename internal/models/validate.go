@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"l0/pkg/er"
+)
+
+// Validate проверяет обязательные поля заказа.
+// Правила общие для всех реализаций хранилища.
+func (o *Order) Validate() error {
+	if o.OrderUID == "" {
+		return fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+	if o.Status != 0 && !o.Status.Valid() {
+		return fmt.Errorf("%w: unknown order status %d", er.ErrInvalidData, o.Status)
+	}
+	if err := o.Delivery.Validate(); err != nil {
+		return fmt.Errorf("delivery creation error: %w", err)
+	}
+	if err := o.Payment.Validate(); err != nil {
+		return fmt.Errorf("payment creation error: %w", err)
+	}
+	for i := range o.Items {
+		if err := o.Items[i].Validate(); err != nil {
+			return fmt.Errorf("item %d creation error: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Validate проверяет данные доставки
+func (d *Delivery) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("%w: recipient name is required", er.ErrInvalidData)
+	}
+	if d.Phone == "" {
+		return fmt.Errorf("%w: recipient phone is required", er.ErrInvalidData)
+	}
+	return nil
+}
+
+// Validate проверяет данные платежа
+func (p *Payment) Validate() error {
+	if p.Transaction == "" {
+		return fmt.Errorf("%w: transaction number is required", er.ErrInvalidData)
+	}
+	if p.Provider == "" {
+		return fmt.Errorf("%w: payment provider is required", er.ErrInvalidData)
+	}
+	if !p.Currency.Valid() {
+		return fmt.Errorf("%w: unknown currency %q, expected ISO 4217 code", er.ErrInvalidData, p.Currency)
+	}
+	if p.Amount <= 0 {
+		return fmt.Errorf("%w: payment amount must be greater than zero", er.ErrInvalidData)
+	}
+	return nil
+}
+
+// Validate проверяет данные товара
+func (i *Item) Validate() error {
+	if i.Name == "" {
+		return fmt.Errorf("%w: item name is required", er.ErrInvalidData)
+	}
+	if i.Price <= 0 {
+		return fmt.Errorf("%w: item price must be greater than zero", er.ErrInvalidData)
+	}
+	return nil
+}
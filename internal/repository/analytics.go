@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"l0/internal/models"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AnalyticsConfig - пересчет агрегатов продаж
+type AnalyticsConfig struct {
+	// RefreshInterval - как часто пересчитываются агрегаты (0 - не пересчитывать)
+	RefreshInterval time.Duration `env:"ANALYTICS_REFRESH_INTERVAL" envDefault:"5m"`
+}
+
+// analyticsRefresher - драйвер, хранящий агрегаты продаж отдельно от заказов
+type analyticsRefresher interface {
+	RefreshAnalytics(ctx context.Context) error
+}
+
+// SalesStats возвращает агрегаты продаж за [query.From, query.To), границы округляются до суток
+func (r *Repository) SalesStats(ctx context.Context, query models.SalesQuery) ([]models.SalesRow, error) {
+	query.RoundToDays()
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	return r.db.SalesStats(ctx, query)
+}
+
+// RefreshAnalytics пересчитывает агрегаты продаж
+func (r *Repository) RefreshAnalytics(ctx context.Context) error {
+	if r.analytics == nil {
+		return nil
+	}
+	return r.analytics.RefreshAnalytics(ctx)
+}
+
+// RunAnalyticsRefresh периодически пересчитывает агрегаты продаж до отмены контекста
+func (r *Repository) RunAnalyticsRefresh(ctx context.Context) {
+	if r.analytics == nil || r.cfg.Analytics.RefreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.Analytics.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RefreshAnalytics(ctx); err != nil && ctx.Err() == nil {
+			zap.S().Errorf("failed to refresh sales analytics: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
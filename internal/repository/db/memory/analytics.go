@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"l0/internal/models"
+	"sort"
+	"time"
+)
+
+type salesKey struct {
+	period   time.Time
+	key      string
+	currency models.Currency
+}
+
+// SalesStats считает агрегаты продаж по заказам в памяти с той же семантикой,
+// что и материализованные представления Postgres
+func (m *Memory) SalesStats(ctx context.Context, query models.SalesQuery) ([]models.SalesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make(map[salesKey]*models.SalesRow)
+	add := func(k salesKey, orders, revenue, deliveryCost int64) {
+		row, ok := stats[k]
+		if !ok {
+			row = &models.SalesRow{Period: k.period, Key: k.key, Currency: k.currency}
+			stats[k] = row
+		}
+		row.Orders += orders
+		row.Revenue += revenue
+		row.DeliveryCost += deliveryCost
+	}
+
+	for uid, order := range m.orders {
+		if _, deleted := m.deleted[uid]; deleted || order.Status == models.StatusCancelled || order.Status == models.StatusReturned {
+			continue
+		}
+		day := models.SalesDaily.Truncate(order.DateCreated)
+		if day.Before(query.From) || !day.Before(query.To) {
+			continue
+		}
+		period := query.Interval.Truncate(day)
+		currency := order.Payment.Currency
+
+		if query.Dimension == models.SalesByBrand {
+			// Заказ учитывается один раз в каждом бренде из своего состава
+			revenue := make(map[string]int64)
+			for _, item := range order.Items {
+				revenue[item.Brand] += item.TotalPrice
+			}
+			for brand, sum := range revenue {
+				add(salesKey{period: period, key: brand, currency: currency}, 1, sum, 0)
+			}
+			continue
+		}
+
+		var key string
+		switch query.Dimension {
+		case models.SalesByDeliveryService:
+			key = order.DeliveryService
+		case models.SalesByProvider:
+			key = order.Payment.Provider
+		case models.SalesByRegion:
+			key = order.Delivery.Region
+		}
+		add(salesKey{period: period, key: key, currency: currency}, 1, order.Payment.Amount, order.Payment.DeliveryCost)
+	}
+
+	result := make([]models.SalesRow, 0, len(stats))
+	for _, row := range stats {
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.Period.Before(b.Period)
+	})
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+)
+
+// GetStatusHistories возвращает истории статусов заказов по order_uid
+func (m *Memory) GetStatusHistories(ctx context.Context, orderUIDs []string) (map[string][]models.StatusChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	histories := make(map[string][]models.StatusChange, len(orderUIDs))
+	for _, orderUID := range orderUIDs {
+		if history, ok := m.history[orderUID]; ok {
+			histories[orderUID] = append([]models.StatusChange(nil), history...)
+		}
+	}
+	return histories, nil
+}
+
+// GetRawOrdersByUIDs возвращает исходные сообщения заказов по order_uid в порядке сохранения
+func (m *Memory) GetRawOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.RawOrder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	raws := make(map[string][]models.RawOrder, len(orderUIDs))
+	for _, orderUID := range orderUIDs {
+		for _, raw := range m.raw[orderUID] {
+			raw.Payload = append([]byte(nil), raw.Payload...)
+			raws[orderUID] = append(raws[orderUID], raw)
+		}
+	}
+	return raws, nil
+}
+
+// ImportOrder создает заказ, ранее вынесенный из хранилища, вместе с его историей статусов
+// и исходными сообщениями. Версия заказа продолжается с сохраненной. В журнал аудита пишется
+// запись archive_restore с версией заказа в архиве.
+func (m *Memory) ImportOrder(ctx context.Context, order models.Order, history []models.StatusChange, raws []models.RawOrder) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.orders[order.OrderUID]; ok {
+		return fmt.Errorf("order creation error: %w: violation of uniqueness", er.ErrOrderExists)
+	}
+	if err := m.checkUnique(order); err != nil {
+		return err
+	}
+
+	imported := cloneOrder(order)
+	imported.Version = max(order.Version+1, 1)
+	imported.NormalizeStatus()
+	_, fields, err := models.AuditDiff(nil, &imported)
+	if err != nil {
+		return err
+	}
+	err = m.appendAudit(ctx, order.OrderUID, models.AuditArchiveRestore, imported.Version,
+		map[string]any{"archive_version": order.Version}, fields)
+	if err != nil {
+		return err
+	}
+	m.put(imported)
+	if len(history) > 0 {
+		m.history[order.OrderUID] = append([]models.StatusChange(nil), history...)
+	} else {
+		m.history[order.OrderUID] = []models.StatusChange{{OrderUID: order.OrderUID, To: imported.Status, ChangedAt: imported.DateCreated}}
+	}
+	for _, raw := range raws {
+		raw.Payload = append([]byte(nil), raw.Payload...)
+		m.raw[order.OrderUID] = append(m.raw[order.OrderUID], raw)
+	}
+	return nil
+}
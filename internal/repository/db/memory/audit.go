@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"l0/internal/models"
+	"time"
+)
+
+// appendAudit дописывает запись журнала аудита. Вызывается под m.mu до изменения заказа,
+// чтобы при ошибке сериализации заказ остался прежним.
+func (m *Memory) appendAudit(ctx context.Context, orderUID string, action models.AuditAction, version int64, before, after any) error {
+	entry := models.AuditEntry{
+		ID:        int64(len(m.audit) + 1),
+		OrderUID:  orderUID,
+		Action:    action,
+		Source:    models.AuditSourceFromContext(ctx),
+		Version:   version,
+		ChangedAt: time.Now(),
+	}
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("audit record marshaling error: %w", err)
+		}
+	}
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("audit record marshaling error: %w", err)
+		}
+	}
+	m.audit = append(m.audit, entry)
+	return nil
+}
+
+// GetOrderAudit возвращает журнал аудита заказа в хронологическом порядке
+func (m *Memory) GetOrderAudit(ctx context.Context, orderUID string) ([]models.AuditEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []models.AuditEntry
+	for _, entry := range m.audit {
+		if entry.OrderUID == orderUID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
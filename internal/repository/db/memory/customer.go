@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"sort"
+)
+
+// GetCustomerSummary возвращает сводку по заказам покупателя и до topBrands самых частых брендов
+func (m *Memory) GetCustomerSummary(ctx context.Context, customerID string, topBrands int) (models.CustomerSummary, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	summary := models.CustomerSummary{CustomerID: customerID}
+	spent := make(map[models.Currency]int64)
+	brands := make(map[string]*models.BrandStat)
+	for uid, order := range m.orders {
+		if _, deleted := m.deleted[uid]; deleted || order.CustomerID != customerID {
+			continue
+		}
+		summary.OrderCount++
+		if summary.FirstOrderAt.IsZero() || order.DateCreated.Before(summary.FirstOrderAt) {
+			summary.FirstOrderAt = order.DateCreated
+		}
+		if order.DateCreated.After(summary.LastOrderAt) {
+			summary.LastOrderAt = order.DateCreated
+		}
+		if order.Status != models.StatusCancelled && order.Status != models.StatusReturned {
+			spent[order.Payment.Currency] += order.Payment.Amount
+		}
+
+		counted := make(map[string]bool)
+		for _, item := range order.Items {
+			if item.Brand == "" {
+				continue
+			}
+			stat, ok := brands[item.Brand]
+			if !ok {
+				stat = &models.BrandStat{Brand: item.Brand}
+				brands[item.Brand] = stat
+			}
+			stat.Items++
+			if !counted[item.Brand] {
+				counted[item.Brand] = true
+				stat.Orders++
+			}
+		}
+	}
+	if summary.OrderCount == 0 {
+		return models.CustomerSummary{}, fmt.Errorf("%w: no orders for customer %s", er.ErrOrderNotFound, customerID)
+	}
+
+	summary.TotalSpent = []models.CustomerSpent{}
+	for currency, amount := range spent {
+		summary.TotalSpent = append(summary.TotalSpent, models.CustomerSpent{Currency: currency, Amount: amount})
+	}
+	sort.Slice(summary.TotalSpent, func(i, j int) bool {
+		return summary.TotalSpent[i].Currency < summary.TotalSpent[j].Currency
+	})
+
+	summary.FavoriteBrands = []models.BrandStat{}
+	for _, stat := range brands {
+		summary.FavoriteBrands = append(summary.FavoriteBrands, *stat)
+	}
+	sort.Slice(summary.FavoriteBrands, func(i, j int) bool {
+		a, b := summary.FavoriteBrands[i], summary.FavoriteBrands[j]
+		if a.Orders != b.Orders {
+			return a.Orders > b.Orders
+		}
+		if a.Items != b.Items {
+			return a.Items > b.Items
+		}
+		return a.Brand < b.Brand
+	})
+	if len(summary.FavoriteBrands) > topBrands {
+		summary.FavoriteBrands = summary.FavoriteBrands[:topBrands]
+	}
+	return summary, nil
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+)
+
+// SoftDeleteOrder помечает заказ удаленным, после чего он не возвращается при чтении.
+// Если expectedVersion не 0, заказ удаляется, только если его версия совпадает.
+func (m *Memory) SoftDeleteOrder(ctx context.Context, orderUID string, expectedVersion int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[orderUID]
+	if _, deleted := m.deleted[orderUID]; !ok || deleted {
+		return fmt.Errorf("order deletion error: %w", er.ErrOrderNotFound)
+	}
+	if expectedVersion != 0 && order.Version != expectedVersion {
+		return fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, order.Version)
+	}
+	order.Version++
+	deletedAt := time.Now()
+	err := m.appendAudit(ctx, orderUID, models.AuditDelete, order.Version,
+		map[string]any{"deleted_at": nil}, map[string]any{"deleted_at": deletedAt})
+	if err != nil {
+		return err
+	}
+	m.orders[orderUID] = order
+	m.deleted[orderUID] = deletedAt
+	return nil
+}
+
+// RestoreOrder снимает пометку удаления с заказа
+func (m *Memory) RestoreOrder(ctx context.Context, orderUID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deletedAt, deleted := m.deleted[orderUID]
+	if !deleted {
+		return fmt.Errorf("order restore error: %w: no deleted order with this order_uid", er.ErrOrderNotFound)
+	}
+	order := m.orders[orderUID]
+	order.Version++
+	err := m.appendAudit(ctx, orderUID, models.AuditRestore, order.Version,
+		map[string]any{"deleted_at": deletedAt}, map[string]any{"deleted_at": nil})
+	if err != nil {
+		return err
+	}
+	delete(m.deleted, orderUID)
+	m.orders[orderUID] = order
+	return nil
+}
+
+// ListExpiredOrders возвращает до limit самых старых заказов, созданных раньше before:
+// мягко удаленные, если deleted, иначе активные
+func (m *Memory) ListExpiredOrders(ctx context.Context, before time.Time, deleted bool, limit int) ([]models.Order, error) {
+	orders := m.sortedOrders(func(order models.Order) bool {
+		_, isDeleted := m.deleted[order.OrderUID]
+		return isDeleted == deleted && order.DateCreated.Before(before)
+	})
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+// PurgeOrders безвозвратно удаляет заказы вместе с историей статусов и исходными сообщениями.
+// В журнал аудита попадает только факт удаления.
+func (m *Memory) PurgeOrders(ctx context.Context, orderUIDs []string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int
+	for _, uid := range orderUIDs {
+		order, ok := m.orders[uid]
+		if !ok {
+			continue
+		}
+		if err := m.appendAudit(ctx, uid, models.AuditPurge, order.Version, nil, nil); err != nil {
+			return purged, err
+		}
+		m.remove(order)
+		delete(m.deleted, uid)
+		delete(m.history, uid)
+		delete(m.raw, uid)
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"l0/internal/models"
+	"time"
+)
+
+type idempotencyID struct {
+	client string
+	key    string
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности. Если ключ уже занят и не старше ttl,
+// возвращает сохраненную запись и false. Ключ без ответа старше lease (обработка прервалась
+// падением процесса) занимается заново.
+func (m *Memory) ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl, lease time.Duration) (models.IdempotencyKey, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := idempotencyID{client: key.Client, key: key.Key}
+	existing, ok := m.idempotency[id]
+	expired := time.Since(existing.CreatedAt) > ttl || (existing.StatusCode == 0 && time.Since(existing.CreatedAt) > lease)
+	if ok && !expired {
+		existing.Response = append([]byte(nil), existing.Response...)
+		return existing, false, nil
+	}
+	key.StatusCode, key.Response, key.CreatedAt = 0, nil, time.Now()
+	m.idempotency[id] = key
+	return key, true, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности
+func (m *Memory) CompleteIdempotencyKey(ctx context.Context, client, key string, statusCode int, response []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := idempotencyID{client: client, key: key}
+	if existing, ok := m.idempotency[id]; ok {
+		existing.StatusCode = statusCode
+		existing.Response = append([]byte(nil), response...)
+		m.idempotency[id] = existing
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ, чтобы запрос можно было повторить
+func (m *Memory) ReleaseIdempotencyKey(ctx context.Context, client, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.idempotency, idempotencyID{client: client, key: key})
+	return nil
+}
+
+// PurgeIdempotencyKeys удаляет ключи старше ttl
+func (m *Memory) PurgeIdempotencyKeys(ctx context.Context, ttl time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int
+	for id, key := range m.idempotency {
+		if time.Since(key.CreatedAt) > ttl {
+			delete(m.idempotency, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
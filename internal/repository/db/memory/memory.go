@@ -0,0 +1,292 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory - хранилище заказов в памяти для локального запуска и тестов.
+// Повторяет семантику Postgres: уникальность order_uid, track_number и
+// payment.transaction, ошибки отображаются на sentinel-ошибки из er.
+type Memory struct {
+	mu           sync.RWMutex
+	orders       map[string]models.Order // [order_uid]Order
+	trackNumbers map[string]string       // [track_number]order_uid
+	transactions map[string]string       // [transaction]order_uid
+	raw          map[string][]models.RawOrder
+	history      map[string][]models.StatusChange
+	deleted      map[string]time.Time // [order_uid]время мягкого удаления
+	audit        []models.AuditEntry
+	rates        map[models.Currency][]models.ExchangeRate // по возрастанию даты
+	idempotency  map[idempotencyID]models.IdempotencyKey
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		orders:       make(map[string]models.Order),
+		trackNumbers: make(map[string]string),
+		transactions: make(map[string]string),
+		raw:          make(map[string][]models.RawOrder),
+		history:      make(map[string][]models.StatusChange),
+		deleted:      make(map[string]time.Time),
+		rates:        make(map[models.Currency][]models.ExchangeRate),
+		idempotency:  make(map[idempotencyID]models.IdempotencyKey),
+	}
+}
+
+// Close ничего не делает: хранилищу в памяти нечего закрывать
+func (m *Memory) Close() {}
+
+// cloneOrder возвращает копию заказа, не разделяющую память с исходным
+func cloneOrder(order models.Order) models.Order {
+	if order.Items != nil {
+		order.Items = append(models.Items(nil), order.Items...)
+	}
+	return order
+}
+
+func (m *Memory) CreateOrder(ctx context.Context, order models.Order) error {
+	return m.createNew(ctx, order, nil)
+}
+
+// CreateOrderWithRaw создает заказ и сохраняет его исходное сообщение под одной блокировкой
+func (m *Memory) CreateOrderWithRaw(ctx context.Context, order models.Order, raw models.RawOrder) error {
+	return m.createNew(ctx, order, &raw)
+}
+
+// createNew создает заказ, если его order_uid, track_number и transaction свободны,
+// и сохраняет исходное сообщение raw, если оно передано
+func (m *Memory) createNew(ctx context.Context, order models.Order, raw *models.RawOrder) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.orders[order.OrderUID]; ok {
+		return fmt.Errorf("order creation error: %w: violation of uniqueness", er.ErrOrderExists)
+	}
+	if err := m.checkUnique(order); err != nil {
+		return err
+	}
+	if err := m.create(ctx, order); err != nil {
+		return err
+	}
+	if raw != nil {
+		raw.Payload = append([]byte(nil), raw.Payload...)
+		m.raw[order.OrderUID] = append(m.raw[order.OrderUID], *raw)
+	}
+	return nil
+}
+
+// ReplaceOrder создает заказ или полностью заменяет данные существующего
+func (m *Memory) ReplaceOrder(ctx context.Context, order models.Order) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkUnique(order); err != nil {
+		return err
+	}
+	old, ok := m.orders[order.OrderUID]
+	if !ok {
+		return m.create(ctx, order)
+	}
+	return m.update(ctx, old, order)
+}
+
+// create сохраняет новый заказ с первой версией и начальной записью истории статусов
+func (m *Memory) create(ctx context.Context, order models.Order) error {
+	order = cloneOrder(order)
+	order.Version = 1
+	order.NormalizeStatus()
+	_, fields, err := models.AuditDiff(nil, &order)
+	if err != nil {
+		return err
+	}
+	if err := m.appendAudit(ctx, order.OrderUID, models.AuditCreate, order.Version, nil, fields); err != nil {
+		return err
+	}
+	m.put(order)
+	m.history[order.OrderUID] = append(m.history[order.OrderUID], models.StatusChange{
+		OrderUID:  order.OrderUID,
+		To:        order.Status,
+		ChangedAt: order.DateCreated,
+	})
+	return nil
+}
+
+// update заменяет данные существующего заказа и увеличивает его версию
+func (m *Memory) update(ctx context.Context, old, order models.Order) error {
+	// Статус меняется только через AppendStatusChange
+	order = cloneOrder(order)
+	order.Status = old.Status
+	order.Version = old.Version + 1
+	order.NormalizeStatus()
+	order.EventVersion = max(old.EventVersion, order.EventVersion)
+	before, after, err := models.AuditDiff(&old, &order)
+	if err != nil {
+		return err
+	}
+	if err := m.appendAudit(ctx, order.OrderUID, models.AuditUpdate, order.Version, before, after); err != nil {
+		return err
+	}
+	m.remove(old)
+	m.put(order)
+	return nil
+}
+
+// checkUnique проверяет уникальность track_number и transaction среди других заказов
+func (m *Memory) checkUnique(order models.Order) error {
+	if uid, ok := m.transactions[order.Payment.Transaction]; ok && uid != order.OrderUID {
+		return fmt.Errorf("payment creation error: %w: violation of uniqueness", er.ErrOrderExists)
+	}
+	if uid, ok := m.trackNumbers[order.TrackNumber]; ok && uid != order.OrderUID && order.TrackNumber != "" {
+		return fmt.Errorf("order creation error: %w: violation of uniqueness", er.ErrOrderExists)
+	}
+	return nil
+}
+
+// put сохраняет копию заказа и обновляет индексы уникальности
+func (m *Memory) put(order models.Order) {
+	m.orders[order.OrderUID] = cloneOrder(order)
+	m.transactions[order.Payment.Transaction] = order.OrderUID
+	if order.TrackNumber != "" {
+		m.trackNumbers[order.TrackNumber] = order.OrderUID
+	}
+}
+
+// remove удаляет заказ и его записи в индексах уникальности
+func (m *Memory) remove(order models.Order) {
+	delete(m.orders, order.OrderUID)
+	delete(m.transactions, order.Payment.Transaction)
+	if order.TrackNumber != "" {
+		delete(m.trackNumbers, order.TrackNumber)
+	}
+}
+
+func (m *Memory) GetOrder(ctx context.Context, orderUID string) (models.Order, error) {
+	if orderUID == "" {
+		return models.Order{}, fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	order, ok := m.orders[orderUID]
+	if _, deleted := m.deleted[orderUID]; !ok || deleted {
+		return models.Order{}, fmt.Errorf("order retrieval error: %w", er.ErrOrderNotFound)
+	}
+	return cloneOrder(order), nil
+}
+
+// GetOrdersByUIDs возвращает найденные заказы из списка, отсутствующие и удаленные пропускаются
+func (m *Memory) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) ([]models.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var orders []models.Order
+	seen := make(map[string]bool, len(orderUIDs))
+	for _, orderUID := range orderUIDs {
+		order, ok := m.orders[orderUID]
+		if _, deleted := m.deleted[orderUID]; !ok || deleted || seen[orderUID] {
+			continue
+		}
+		seen[orderUID] = true
+		orders = append(orders, cloneOrder(order))
+	}
+	return orders, nil
+}
+
+func (m *Memory) GetOrders(ctx context.Context) ([]models.Order, error) {
+	return m.filterOrders(func(models.Order) bool { return true }), nil
+}
+
+// ListOrders возвращает до limit заказов после курсора в порядке (date_created, order_uid)
+func (m *Memory) ListOrders(ctx context.Context, filter models.OrderFilter, after *models.Cursor, limit int) ([]models.Order, error) {
+	orders := m.filterOrders(func(order models.Order) bool {
+		if !matchFilter(order, filter) {
+			return false
+		}
+		if after == nil {
+			return true
+		}
+		if !order.DateCreated.Equal(after.DateCreated) {
+			return order.DateCreated.After(after.DateCreated)
+		}
+		return order.OrderUID > after.OrderUID
+	})
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+// matchFilter проверяет, подходит ли заказ под фильтр
+func matchFilter(order models.Order, filter models.OrderFilter) bool {
+	switch {
+	case filter.TrackNumber != "" && order.TrackNumber != filter.TrackNumber,
+		filter.CustomerID != "" && order.CustomerID != filter.CustomerID,
+		filter.Phone != "" && order.Delivery.Phone != filter.Phone,
+		filter.Email != "" && !strings.EqualFold(order.Delivery.Email, filter.Email),
+		filter.DeliveryService != "" && order.DeliveryService != filter.DeliveryService,
+		!filter.DateFrom.IsZero() && order.DateCreated.Before(filter.DateFrom),
+		!filter.DateTo.IsZero() && !order.DateCreated.Before(filter.DateTo),
+		filter.PaymentProvider != "" && order.Payment.Provider != filter.PaymentProvider,
+		filter.Bank != "" && order.Payment.Bank != filter.Bank:
+		return false
+	}
+
+	if !filter.HasItemConditions() {
+		return true
+	}
+	for _, item := range order.Items {
+		if (filter.Brand == "" || item.Brand == filter.Brand) &&
+			(filter.NmID == 0 || item.NmID == filter.NmID) &&
+			(filter.ChrtID == 0 || item.ChrtID == filter.ChrtID) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterOrders возвращает копии подходящих неудаленных заказов, упорядоченные по date_created и order_uid
+func (m *Memory) filterOrders(match func(models.Order) bool) []models.Order {
+	return m.sortedOrders(func(order models.Order) bool {
+		if _, deleted := m.deleted[order.OrderUID]; deleted {
+			return false
+		}
+		return match(order)
+	})
+}
+
+// sortedOrders возвращает копии подходящих заказов, включая удаленные,
+// упорядоченные по date_created и order_uid
+func (m *Memory) sortedOrders(match func(models.Order) bool) []models.Order {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var orders []models.Order
+	for _, order := range m.orders {
+		if match(order) {
+			orders = append(orders, cloneOrder(order))
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].DateCreated.Equal(orders[j].DateCreated) {
+			return orders[i].DateCreated.Before(orders[j].DateCreated)
+		}
+		return orders[i].OrderUID < orders[j].OrderUID
+	})
+	return orders
+}
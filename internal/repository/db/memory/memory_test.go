@@ -0,0 +1,333 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testOrder возвращает минимальный корректный заказ
+func testOrder(uid string) models.Order {
+	return models.Order{
+		OrderUID:    uid,
+		TrackNumber: "track-" + uid,
+		Delivery:    models.Delivery{Name: "Test Testov", Phone: "+9720000000"},
+		Payment: models.Payment{
+			Transaction: "tx-" + uid,
+			Currency:    "USD",
+			Provider:    "wbpay",
+			Amount:      1817,
+			GoodsTotal:  317,
+		},
+		Items:       models.Items{{ChrtID: 9934930, TrackNumber: "track-" + uid, Price: 453, Name: "Mascaras", TotalPrice: 317}},
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+	}
+}
+
+func TestCreateOrderUniqueness(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(o *models.Order)
+		wantErr error
+	}{
+		{"different order", func(o *models.Order) {}, nil},
+		{"same order_uid", func(o *models.Order) { o.OrderUID = "a" }, er.ErrOrderExists},
+		{"same transaction", func(o *models.Order) { o.Payment.Transaction = "tx-a" }, er.ErrOrderExists},
+		{"same track_number", func(o *models.Order) { o.TrackNumber = "track-a" }, er.ErrOrderExists},
+		{"invalid order", func(o *models.Order) { o.Delivery.Name = "" }, er.ErrInvalidData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemory()
+			if err := m.CreateOrder(ctx, testOrder("a")); err != nil {
+				t.Fatalf("create first order: %v", err)
+			}
+
+			order := testOrder("b")
+			tt.modify(&order)
+			err := m.CreateOrder(ctx, order)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && order.OrderUID != "a" {
+				if _, err := m.GetOrder(ctx, order.OrderUID); !errors.Is(err, er.ErrOrderNotFound) {
+					t.Fatalf("rejected order is stored: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateOrderWithRaw(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	if err := m.CreateOrder(ctx, testOrder("a")); err != nil {
+		t.Fatalf("create first order: %v", err)
+	}
+
+	raw := models.RawOrder{OrderUID: "a", Payload: []byte(`{"order_uid":"a"}`), Topic: models.RawTopicHTTP}
+	if err := m.CreateOrderWithRaw(ctx, testOrder("a"), raw); !errors.Is(err, er.ErrOrderExists) {
+		t.Fatalf("CreateOrderWithRaw() error = %v, want %v", err, er.ErrOrderExists)
+	}
+	if raws, _ := m.GetRawOrders(ctx, "a"); len(raws) != 0 {
+		t.Fatalf("raw payload of rejected order is stored: %d messages", len(raws))
+	}
+
+	raw.OrderUID = "b"
+	if err := m.CreateOrderWithRaw(ctx, testOrder("b"), raw); err != nil {
+		t.Fatalf("CreateOrderWithRaw(): %v", err)
+	}
+	if raws, _ := m.GetRawOrders(ctx, "b"); len(raws) != 1 {
+		t.Fatalf("got %d raw messages, want 1", len(raws))
+	}
+}
+
+func TestOrderVersions(t *testing.T) {
+	accept := &models.StatusChange{OrderUID: "a", From: models.StatusCreated, To: models.StatusAccepted}
+	tests := []struct {
+		name        string
+		apply       func(ctx context.Context, m *Memory, order models.Order) error
+		wantErr     error
+		wantVersion int64
+		wantStatus  models.Status
+	}{
+		{
+			name: "update",
+			apply: func(ctx context.Context, m *Memory, order models.Order) error {
+				return m.UpdateOrder(ctx, order, 1)
+			},
+			wantVersion: 2,
+			wantStatus:  models.StatusCreated,
+		},
+		{
+			name: "update with stale version",
+			apply: func(ctx context.Context, m *Memory, order models.Order) error {
+				return m.UpdateOrder(ctx, order, 2)
+			},
+			wantErr:     er.ErrVersionConflict,
+			wantVersion: 1,
+			wantStatus:  models.StatusCreated,
+		},
+		{
+			name: "status change",
+			apply: func(ctx context.Context, m *Memory, order models.Order) error {
+				return m.AppendStatusChange(ctx, *accept, 1)
+			},
+			wantVersion: 2,
+			wantStatus:  models.StatusAccepted,
+		},
+		{
+			name: "status change with stale version",
+			apply: func(ctx context.Context, m *Memory, order models.Order) error {
+				return m.AppendStatusChange(ctx, *accept, 5)
+			},
+			wantErr:     er.ErrVersionConflict,
+			wantVersion: 1,
+			wantStatus:  models.StatusCreated,
+		},
+		{
+			name: "patch with status change bumps version once",
+			apply: func(ctx context.Context, m *Memory, order models.Order) error {
+				return m.PatchOrder(ctx, order, accept, 1)
+			},
+			wantVersion: 2,
+			wantStatus:  models.StatusAccepted,
+		},
+		{
+			name: "patch with forbidden status",
+			apply: func(ctx context.Context, m *Memory, order models.Order) error {
+				return m.PatchOrder(ctx, order, &models.StatusChange{OrderUID: "a", From: models.StatusShipped, To: models.StatusDelivered}, 1)
+			},
+			wantErr:     er.ErrInvalidStatusTransition,
+			wantVersion: 1,
+			wantStatus:  models.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemory()
+			order := testOrder("a")
+			if err := m.CreateOrder(ctx, order); err != nil {
+				t.Fatalf("create order: %v", err)
+			}
+
+			order.Delivery.Address = "Ploshad Mira 15"
+			err := tt.apply(ctx, m, order)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			got, err := m.GetOrder(ctx, "a")
+			if err != nil {
+				t.Fatalf("get order: %v", err)
+			}
+			if got.Version != tt.wantVersion || got.Status != tt.wantStatus {
+				t.Fatalf("version %d, status %s; want %d, %s", got.Version, got.Status, tt.wantVersion, tt.wantStatus)
+			}
+			for _, item := range got.Items {
+				if item.Status != got.Status {
+					t.Fatalf("item status %s differs from order status %s", item.Status, got.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		apply       func(ctx context.Context, m *Memory) error
+		wantErr     error
+		wantFound   bool
+		wantVersion int64
+	}{
+		{
+			name:  "delete",
+			apply: func(ctx context.Context, m *Memory) error { return m.SoftDeleteOrder(ctx, "a", 0) },
+		},
+		{
+			name:        "delete with stale version",
+			apply:       func(ctx context.Context, m *Memory) error { return m.SoftDeleteOrder(ctx, "a", 3) },
+			wantErr:     er.ErrVersionConflict,
+			wantFound:   true,
+			wantVersion: 1,
+		},
+		{
+			name: "delete twice",
+			apply: func(ctx context.Context, m *Memory) error {
+				if err := m.SoftDeleteOrder(ctx, "a", 1); err != nil {
+					return err
+				}
+				return m.SoftDeleteOrder(ctx, "a", 0)
+			},
+			wantErr: er.ErrOrderNotFound,
+		},
+		{
+			name: "delete and restore",
+			apply: func(ctx context.Context, m *Memory) error {
+				if err := m.SoftDeleteOrder(ctx, "a", 0); err != nil {
+					return err
+				}
+				return m.RestoreOrder(ctx, "a")
+			},
+			wantFound:   true,
+			wantVersion: 3,
+		},
+		{
+			name:        "restore active order",
+			apply:       func(ctx context.Context, m *Memory) error { return m.RestoreOrder(ctx, "a") },
+			wantErr:     er.ErrOrderNotFound,
+			wantFound:   true,
+			wantVersion: 1,
+		},
+		{
+			name: "update deleted order",
+			apply: func(ctx context.Context, m *Memory) error {
+				if err := m.SoftDeleteOrder(ctx, "a", 0); err != nil {
+					return err
+				}
+				return m.UpdateOrder(ctx, testOrder("a"), 2)
+			},
+			wantErr: er.ErrOrderNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemory()
+			if err := m.CreateOrder(ctx, testOrder("a")); err != nil {
+				t.Fatalf("create order: %v", err)
+			}
+
+			err := tt.apply(ctx, m)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			got, err := m.GetOrder(ctx, "a")
+			if found := err == nil; found != tt.wantFound {
+				t.Fatalf("GetOrder() error = %v, want found %t", err, tt.wantFound)
+			}
+			if tt.wantFound && got.Version != tt.wantVersion {
+				t.Fatalf("version %d, want %d", got.Version, tt.wantVersion)
+			}
+			orders, err := m.GetOrdersByUIDs(ctx, []string{"a"})
+			if err != nil || (len(orders) == 1) != tt.wantFound {
+				t.Fatalf("GetOrdersByUIDs() = %d orders, %v; want found %t", len(orders), err, tt.wantFound)
+			}
+			// Удаленный заказ по-прежнему занимает order_uid
+			if !tt.wantFound {
+				if err := m.CreateOrder(ctx, testOrder("a")); !errors.Is(err, er.ErrOrderExists) {
+					t.Fatalf("create over deleted order: %v, want %v", err, er.ErrOrderExists)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyOrderEventOrdering(t *testing.T) {
+	tests := []struct {
+		name        string
+		events      []int64 // event_version событий в порядке поступления
+		wantApplied []bool
+		wantEvent   int64 // event_version, данные которого остались в заказе
+		wantVersion int64
+	}{
+		{"in order", []int64{1, 2, 3}, []bool{true, true, true}, 3, 3},
+		{"out of order", []int64{1, 3, 2}, []bool{true, true, false}, 3, 2},
+		{"duplicate", []int64{2, 2}, []bool{true, false}, 2, 1},
+		{"without event_version", []int64{0, 0}, []bool{true, false}, 0, 1},
+		{"older than first", []int64{5, 1}, []bool{true, false}, 5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemory()
+			for i, eventVersion := range tt.events {
+				order := testOrder("a")
+				order.EventVersion = eventVersion
+				order.Delivery.City = "event " + strconv.FormatInt(eventVersion, 10)
+				applied, err := m.ApplyOrderEvent(ctx, order)
+				if err != nil {
+					t.Fatalf("event %d: %v", i+1, err)
+				}
+				if applied != tt.wantApplied[i] {
+					t.Fatalf("event %d applied = %t, want %t", i+1, applied, tt.wantApplied[i])
+				}
+			}
+
+			got, err := m.GetOrder(ctx, "a")
+			if err != nil {
+				t.Fatalf("get order: %v", err)
+			}
+			if got.EventVersion != tt.wantEvent || got.Version != tt.wantVersion {
+				t.Fatalf("event_version %d, version %d; want %d, %d", got.EventVersion, got.Version, tt.wantEvent, tt.wantVersion)
+			}
+			if want := "event " + strconv.FormatInt(tt.wantEvent, 10); got.Delivery.City != want {
+				t.Fatalf("delivery from %q, want %q", got.Delivery.City, want)
+			}
+		})
+	}
+}
+
+func TestApplyOrderEventDeleted(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	if _, err := m.ApplyOrderEvent(ctx, testOrder("a")); err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	if err := m.SoftDeleteOrder(ctx, "a", 0); err != nil {
+		t.Fatalf("delete order: %v", err)
+	}
+
+	order := testOrder("a")
+	order.EventVersion = 10
+	applied, err := m.ApplyOrderEvent(ctx, order)
+	if err != nil || applied {
+		t.Fatalf("event for deleted order applied = %t, %v", applied, err)
+	}
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"sort"
+	"time"
+)
+
+// SaveExchangeRates сохраняет курсы, заменяя уже загруженные на те же даты
+func (m *Memory) SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rate := range rates {
+		history := m.rates[rate.Currency]
+		i := sort.Search(len(history), func(i int) bool { return !history[i].Date.Before(rate.Date) })
+		if i < len(history) && history[i].Date.Equal(rate.Date) {
+			history[i] = rate
+			continue
+		}
+		history = append(history, models.ExchangeRate{})
+		copy(history[i+1:], history[i:])
+		history[i] = rate
+		m.rates[rate.Currency] = history
+	}
+	return len(rates), nil
+}
+
+// GetExchangeRate возвращает курс валюты, действующий на дату date
+func (m *Memory) GetExchangeRate(ctx context.Context, currency models.Currency, date time.Time) (models.ExchangeRate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.rates[currency]
+	i := sort.Search(len(history), func(i int) bool { return history[i].Date.After(date) })
+	if i == 0 {
+		return models.ExchangeRate{}, fmt.Errorf("%w: no %s rate on %s", er.ErrRateNotFound, currency, date.Format(time.DateOnly))
+	}
+	return history[i-1], nil
+}
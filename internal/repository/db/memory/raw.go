@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"l0/internal/models"
+)
+
+// SaveRawOrder сохраняет исходное сообщение заказа.
+// Повторно доставленное сообщение Kafka (тот же topic/partition/offset) игнорируется;
+// у сообщений HTTP API нет offset, они сохраняются всегда.
+func (m *Memory) SaveRawOrder(ctx context.Context, raw models.RawOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if raw.Topic != "" && raw.Topic != models.RawTopicHTTP {
+		for _, saved := range m.raw[raw.OrderUID] {
+			if saved.Topic == raw.Topic && saved.Partition == raw.Partition && saved.Offset == raw.Offset {
+				return nil
+			}
+		}
+	}
+	raw.Payload = append([]byte(nil), raw.Payload...)
+	m.raw[raw.OrderUID] = append(m.raw[raw.OrderUID], raw)
+	return nil
+}
+
+// GetRawOrders возвращает исходные сообщения заказа, начиная с самого нового
+func (m *Memory) GetRawOrders(ctx context.Context, orderUID string) ([]models.RawOrder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	saved := m.raw[orderUID]
+	raws := make([]models.RawOrder, 0, len(saved))
+	for i := len(saved) - 1; i >= 0; i-- {
+		raw := saved[i]
+		raw.Payload = append([]byte(nil), raw.Payload...)
+		raws = append(raws, raw)
+	}
+	return raws, nil
+}
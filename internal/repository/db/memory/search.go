@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"l0/internal/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// searchDocument собирает текст заказа в том же составе, что и поисковый документ Postgres
+func searchDocument(order models.Order) (items, delivery string) {
+	parts := make([]string, 0, len(order.Items)*2)
+	for _, item := range order.Items {
+		parts = append(parts, item.Brand, item.Name)
+	}
+	return strings.Join(parts, " "), strings.Join([]string{order.Delivery.Name, order.Delivery.City, order.Delivery.Address}, " ")
+}
+
+// tokenize разбивает текст на слова в нижнем регистре
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchOrdersFullText ищет заказы, содержащие все слова запроса.
+// Упрощенный аналог Postgres: совпадение в товарах весит больше, чем в доставке.
+func (m *Memory) SearchOrdersFullText(ctx context.Context, text string, limit int) ([]models.SearchHit, error) {
+	terms := tokenize(text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	m.mu.RLock()
+	var hits []models.SearchHit
+	for _, order := range m.orders {
+		if _, deleted := m.deleted[order.OrderUID]; deleted {
+			continue
+		}
+		items, delivery := searchDocument(order)
+		itemWords, deliveryWords := countWords(items), countWords(delivery)
+
+		var rank float64
+		matched := true
+		for _, term := range terms {
+			if itemWords[term] == 0 && deliveryWords[term] == 0 {
+				matched = false
+				break
+			}
+			rank += float64(itemWords[term]) + 0.4*float64(deliveryWords[term])
+		}
+		if !matched {
+			continue
+		}
+
+		hits = append(hits, models.SearchHit{
+			OrderUID: order.OrderUID,
+			Rank:     rank / float64(len(tokenize(items))+len(tokenize(delivery))),
+			Snippet:  highlight(strings.TrimSpace(items+" "+delivery), terms),
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].OrderUID < hits[j].OrderUID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// countWords считает вхождения слов текста
+func countWords(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range tokenize(text) {
+		counts[word]++
+	}
+	return counts
+}
+
+// highlight оборачивает слова запроса маркерами подсветки
+func highlight(text string, terms []string) string {
+	want := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		want[term] = true
+	}
+
+	words := strings.Fields(text)
+	for i, word := range words {
+		trimmed := strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if trimmed != "" && want[strings.ToLower(trimmed)] {
+			words[i] = strings.Replace(word, trimmed, models.HighlightStart+trimmed+models.HighlightStop, 1)
+		}
+	}
+	return strings.Join(words, " ")
+}
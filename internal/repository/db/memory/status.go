@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+)
+
+// AppendStatusChange переводит заказ и его товары в новый статус и дописывает историю.
+// Переход выполняется, только если текущий статус заказа равен change.From,
+// а версия - expectedVersion (0 - без проверки версии).
+func (m *Memory) AppendStatusChange(ctx context.Context, change models.StatusChange, expectedVersion int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[change.OrderUID]
+	if _, deleted := m.deleted[change.OrderUID]; !ok || deleted {
+		return fmt.Errorf("order retrieval error: %w", er.ErrOrderNotFound)
+	}
+	if expectedVersion != 0 && order.Version != expectedVersion {
+		return fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, order.Version)
+	}
+	if order.Status != change.From {
+		return fmt.Errorf("%w: order status is already %s", er.ErrInvalidStatusTransition, order.Status)
+	}
+	return m.changeStatus(ctx, order, change, true)
+}
+
+// changeStatus переводит заказ и его товары в статус change.To. Вызывается под m.mu.
+// Если bump false, версия не увеличивается: ее уже увеличило изменение данных заказа.
+func (m *Memory) changeStatus(ctx context.Context, order models.Order, change models.StatusChange, bump bool) error {
+	order = cloneOrder(order)
+	order.Status = change.To
+	if bump {
+		order.Version++
+	}
+	for i := range order.Items {
+		order.Items[i].Status = change.To
+	}
+	err := m.appendAudit(ctx, order.OrderUID, models.AuditStatus, order.Version,
+		map[string]any{"status": change.From.String()},
+		map[string]any{"status": change.To.String(), "comment": change.Comment})
+	if err != nil {
+		return err
+	}
+	m.orders[order.OrderUID] = order
+	m.history[order.OrderUID] = append(m.history[order.OrderUID], change)
+	return nil
+}
+
+// GetStatusHistory возвращает историю статусов заказа в хронологическом порядке
+func (m *Memory) GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]models.StatusChange(nil), m.history[orderUID]...), nil
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+)
+
+// UpdateOrder перезаписывает данные заказа, если его текущая версия равна expectedVersion
+func (m *Memory) UpdateOrder(ctx context.Context, order models.Order, expectedVersion int64) error {
+	return m.PatchOrder(ctx, order, nil, expectedVersion)
+}
+
+// PatchOrder перезаписывает данные заказа и, если change не nil, меняет его статус.
+// Заказ изменяется, только если его текущая версия равна expectedVersion.
+func (m *Memory) PatchOrder(ctx context.Context, order models.Order, change *models.StatusChange, expectedVersion int64) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.orders[order.OrderUID]
+	if _, deleted := m.deleted[order.OrderUID]; !ok || deleted {
+		return fmt.Errorf("order retrieval error: %w", er.ErrOrderNotFound)
+	}
+	if old.Version != expectedVersion {
+		return fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, old.Version)
+	}
+	if change != nil && old.Status != change.From {
+		return fmt.Errorf("%w: order status is already %s", er.ErrInvalidStatusTransition, old.Status)
+	}
+	if err := m.checkUnique(order); err != nil {
+		return err
+	}
+	if err := m.update(ctx, old, order); err != nil {
+		return err
+	}
+	if change != nil {
+		return m.changeStatus(ctx, m.orders[order.OrderUID], *change, false)
+	}
+	return nil
+}
+
+// ApplyOrderEvent создает заказ из события или обновляет существующий, если версия события
+// новее уже примененной. Возвращает false, если событие устарело и проигнорировано.
+func (m *Memory) ApplyOrderEvent(ctx context.Context, order models.Order) (bool, error) {
+	if err := order.Validate(); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.orders[order.OrderUID]
+	if _, deleted := m.deleted[order.OrderUID]; deleted || (ok && order.EventVersion <= old.EventVersion) {
+		return false, nil
+	}
+	if err := m.checkUnique(order); err != nil {
+		return false, err
+	}
+	var err error
+	if !ok {
+		err = m.create(ctx, order)
+	} else {
+		err = m.update(ctx, old, order)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetOrderChanges возвращает версии активных заказов, измененных позже since, и заказы,
+// удаленные позже since. Каждое изменение заказа пишется в журнал аудита, поэтому изменения
+// читаются с его конца. При нулевом since возвращаются версии всех активных заказов.
+func (m *Memory) GetOrderChanges(ctx context.Context, since time.Time) (models.OrderChanges, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	changes := models.OrderChanges{Versions: make(map[string]int64), Watermark: time.Now()}
+	if since.IsZero() {
+		for orderUID, order := range m.orders {
+			if _, deleted := m.deleted[orderUID]; !deleted {
+				changes.Versions[orderUID] = order.Version
+			}
+		}
+		return changes, nil
+	}
+
+	seen := make(map[string]bool)
+	for i := len(m.audit) - 1; i >= 0 && m.audit[i].ChangedAt.After(since); i-- {
+		orderUID := m.audit[i].OrderUID
+		if seen[orderUID] {
+			continue
+		}
+		seen[orderUID] = true
+		order, ok := m.orders[orderUID]
+		if _, deleted := m.deleted[orderUID]; ok && !deleted {
+			changes.Versions[orderUID] = order.Version
+		} else {
+			changes.Removed = append(changes.Removed, orderUID)
+		}
+	}
+	return changes, nil
+}
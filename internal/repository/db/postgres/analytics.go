@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+)
+
+// salesSource - представление и выражения для разреза аналитики
+type salesSource struct {
+	view         string
+	key          string
+	deliveryCost string
+}
+
+var salesSources = map[models.SalesDimension]salesSource{
+	models.SalesTotal:             {view: "sales_daily", key: "''", deliveryCost: "delivery_cost"},
+	models.SalesByDeliveryService: {view: "sales_daily", key: "delivery_service", deliveryCost: "delivery_cost"},
+	models.SalesByProvider:        {view: "sales_daily", key: "provider", deliveryCost: "delivery_cost"},
+	models.SalesByRegion:          {view: "sales_daily", key: "region", deliveryCost: "delivery_cost"},
+	models.SalesByBrand:           {view: "sales_brand_daily", key: "brand", deliveryCost: "0"},
+}
+
+// SalesStats возвращает агрегаты продаж из материализованных представлений,
+// упорядоченные по значению разреза, валюте и периоду
+func (p *Postgres) SalesStats(ctx context.Context, query models.SalesQuery) ([]models.SalesRow, error) {
+	source, ok := salesSources[query.Dimension]
+	if !ok {
+		return nil, fmt.Errorf("unsupported analytics dimension %q", query.Dimension)
+	}
+	period := "day"
+	if query.Interval == models.SalesWeekly {
+		period = "date_trunc('week', day)::date"
+	}
+	sql := `SELECT ` + period + `, ` + source.key + `, currency,
+			sum(orders)::bigint, sum(revenue)::bigint, sum(` + source.deliveryCost + `)::bigint
+		FROM ` + source.view + `
+		WHERE day >= $1::date AND day < $2::date
+		GROUP BY 1, 2, 3
+		ORDER BY 2, 3, 1`
+
+	var result []models.SalesRow
+	err := p.read(ctx, func(q querier) error {
+		result = nil
+		rows, err := q.Query(ctx, sql, query.From, query.To)
+		if err != nil {
+			return fmt.Errorf("sales analytics retrieval error: %w", checkPostgresError(err))
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var row models.SalesRow
+			if err := rows.Scan(&row.Period, &row.Key, &row.Currency, &row.Orders, &row.Revenue, &row.DeliveryCost); err != nil {
+				return fmt.Errorf("sales analytics retrieval error: %w", checkPostgresError(err))
+			}
+			result = append(result, row)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("sales analytics retrieval error: %w", checkPostgresError(err))
+		}
+		return nil
+	})
+	return result, err
+}
+
+// RefreshAnalytics пересчитывает материализованные агрегаты продаж, не блокируя чтение
+func (p *Postgres) RefreshAnalytics(ctx context.Context) error {
+	for _, view := range []string{"sales_daily", "sales_brand_daily"} {
+		if _, err := p.pool.Exec(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY `+view); err != nil {
+			return fmt.Errorf("%s refresh error: %w", view, checkPostgresError(err))
+		}
+	}
+	return nil
+}
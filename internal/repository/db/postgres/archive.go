@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetStatusHistories возвращает истории статусов заказов одним запросом, по order_uid
+func (p *Postgres) GetStatusHistories(ctx context.Context, orderUIDs []string) (map[string][]models.StatusChange, error) {
+	query := `SELECT order_uid, from_status, to_status, comment, changed_at
+		FROM order_status_history WHERE order_uid = ANY($1) ORDER BY id`
+	rows, err := p.pool.Query(ctx, query, orderUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("status history query error: %w", checkPostgresError(err))
+	}
+	defer rows.Close()
+
+	histories := make(map[string][]models.StatusChange, len(orderUIDs))
+	for rows.Next() {
+		var change models.StatusChange
+		if err := rows.Scan(&change.OrderUID, &change.From, &change.To, &change.Comment, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("status history scanning error: %w", checkPostgresError(err))
+		}
+		histories[change.OrderUID] = append(histories[change.OrderUID], change)
+	}
+
+	// Проверка на ошибки итерации
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("status history iteration error: %w", checkPostgresError(err))
+	}
+
+	return histories, nil
+}
+
+// GetRawOrdersByUIDs возвращает исходные сообщения заказов одним запросом, по order_uid
+// в порядке сохранения
+func (p *Postgres) GetRawOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.RawOrder, error) {
+	query := `SELECT order_uid, payload, topic, kafka_partition, kafka_offset, ingested_at, major_units
+		FROM order_raw WHERE order_uid = ANY($1) ORDER BY id`
+	rows, err := p.pool.Query(ctx, query, orderUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("raw order query error: %w", checkPostgresError(err))
+	}
+	defer rows.Close()
+
+	raws := make(map[string][]models.RawOrder, len(orderUIDs))
+	for rows.Next() {
+		var raw models.RawOrder
+		var payload []byte
+		if err := rows.Scan(&raw.OrderUID, &payload, &raw.Topic, &raw.Partition, &raw.Offset, &raw.IngestedAt, &raw.MajorUnits); err != nil {
+			return nil, fmt.Errorf("raw order scanning error: %w", checkPostgresError(err))
+		}
+		raw.Payload = payload
+		raws[raw.OrderUID] = append(raws[raw.OrderUID], raw)
+	}
+
+	// Проверка на ошибки итерации
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("raw order iteration error: %w", checkPostgresError(err))
+	}
+
+	return raws, nil
+}
+
+// ImportOrder создает заказ, ранее вынесенный из БД, вместе с его историей статусов и исходными
+// сообщениями. Версия заказа продолжается с сохраненной, чтобы старые ETag не совпали с новыми.
+// В журнал аудита пишется запись archive_restore с версией заказа в архиве.
+func (p *Postgres) ImportOrder(ctx context.Context, order models.Order, history []models.StatusChange, raws []models.RawOrder) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	return p.withTx(ctx, func(tx pgx.Tx) error {
+		restored, err := p.insertOrderTx(ctx, tx, order)
+		if err != nil {
+			return err
+		}
+
+		restored.Version = max(order.Version+1, 1)
+		query := `UPDATE orders SET version = $2 WHERE order_uid = $1 AND date_created = ` + partitionKey("$1")
+		if _, err := tx.Exec(ctx, query, order.OrderUID, restored.Version); err != nil {
+			return fmt.Errorf("order version update error: %w", checkPostgresError(err))
+		}
+
+		// Сохраненная история заменяет начальную запись, созданную insertOrderTx
+		if len(history) > 0 {
+			if _, err := tx.Exec(ctx, `DELETE FROM order_status_history WHERE order_uid = $1`, order.OrderUID); err != nil {
+				return fmt.Errorf("status history deletion error: %w", checkPostgresError(err))
+			}
+		}
+		for _, change := range history {
+			_, err := tx.Exec(ctx, `INSERT INTO order_status_history (order_uid, from_status, to_status, comment, changed_at) VALUES ($1, $2, $3, $4, $5)`,
+				order.OrderUID, change.From, change.To, change.Comment, change.ChangedAt)
+			if err != nil {
+				return fmt.Errorf("status history creation error: %w", checkPostgresError(err))
+			}
+		}
+
+		for _, raw := range raws {
+			raw.OrderUID = order.OrderUID
+			if err := insertRawOrderTx(ctx, tx, raw); err != nil {
+				return err
+			}
+		}
+
+		_, fields, err := models.AuditDiff(nil, &restored)
+		if err != nil {
+			return err
+		}
+		return auditTx(ctx, tx, order.OrderUID, models.AuditArchiveRestore, restored.Version,
+			map[string]any{"archive_version": order.Version}, fields)
+	})
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// auditTx дописывает запись журнала аудита в транзакции изменения заказа.
+// Источник изменения берется из контекста; before и after сериализуются в JSON, nil - NULL.
+func auditTx(ctx context.Context, tx pgx.Tx, orderUID string, action models.AuditAction, version int64, before, after any) error {
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+
+	source := models.AuditSourceFromContext(ctx)
+	_, err = tx.Exec(ctx, `INSERT INTO order_audit (order_uid, action, source, actor, version, before, after) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+		orderUID, action, source.Kind, source.Actor, version, beforeJSON, afterJSON)
+	if err != nil {
+		return fmt.Errorf("audit record creation error: %w", checkPostgresError(err))
+	}
+	return nil
+}
+
+func auditJSON(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("audit record marshaling error: %w", err)
+	}
+	return data, nil
+}
+
+// loadOrderTx читает заказ внутри транзакции, включая мягко удаленный, для записи в журнал аудита
+func loadOrderTx(ctx context.Context, tx pgx.Tx, orderUID string) (models.Order, error) {
+	orders, err := queryOrders(ctx, tx, `WHERE o.order_uid = $1 AND o.date_created = `+partitionKey("$1"), orderUID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if len(orders) == 0 {
+		return models.Order{}, fmt.Errorf("order retrieval error: %w", er.ErrOrderNotFound)
+	}
+	return orders[0], nil
+}
+
+// GetOrderAudit возвращает журнал аудита заказа в хронологическом порядке.
+// Журнал сохраняется и после безвозвратного удаления заказа.
+func (p *Postgres) GetOrderAudit(ctx context.Context, orderUID string) ([]models.AuditEntry, error) {
+	query := `SELECT id, order_uid, action, source, actor, version, before, after, changed_at
+		FROM order_audit WHERE order_uid = $1 ORDER BY id`
+	rows, err := p.pool.Query(ctx, query, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("audit query error: %w", checkPostgresError(err))
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		err := rows.Scan(&entry.ID, &entry.OrderUID, &entry.Action, &entry.Source.Kind, &entry.Source.Actor,
+			&entry.Version, &entry.Before, &entry.After, &entry.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("audit record scanning error: %w", checkPostgresError(err))
+		}
+		entries = append(entries, entry)
+	}
+
+	// Проверка на ошибки итерации
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("audit iteration error: %w", checkPostgresError(err))
+	}
+
+	return entries, nil
+}
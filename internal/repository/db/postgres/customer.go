@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+)
+
+// GetCustomerSummary возвращает сводку по заказам покупателя и до topBrands самых частых брендов
+func (p *Postgres) GetCustomerSummary(ctx context.Context, customerID string, topBrands int) (models.CustomerSummary, error) {
+	var summary models.CustomerSummary
+	err := p.read(ctx, func(q querier) error {
+		summary = models.CustomerSummary{CustomerID: customerID}
+
+		var first, last *time.Time
+		err := q.QueryRow(ctx, `SELECT count(*), min(date_created), max(date_created) FROM orders
+			WHERE customer_id = $1 AND deleted_at IS NULL`, customerID).Scan(&summary.OrderCount, &first, &last)
+		if err != nil {
+			return fmt.Errorf("customer summary retrieval error: %w", checkPostgresError(err))
+		}
+		if summary.OrderCount == 0 {
+			return fmt.Errorf("%w: no orders for customer %s", er.ErrOrderNotFound, customerID)
+		}
+		summary.FirstOrderAt, summary.LastOrderAt = *first, *last
+
+		rows, err := q.Query(ctx, `SELECT p.currency, sum(p.amount)::bigint
+			FROM orders o JOIN payment p ON p.id = o.payment_id
+			WHERE o.customer_id = $1 AND o.deleted_at IS NULL AND o.status NOT IN ($2, $3)
+			GROUP BY 1 ORDER BY 1`, customerID, models.StatusCancelled, models.StatusReturned)
+		if err != nil {
+			return fmt.Errorf("customer summary retrieval error: %w", checkPostgresError(err))
+		}
+		summary.TotalSpent = []models.CustomerSpent{}
+		for rows.Next() {
+			var spent models.CustomerSpent
+			if err := rows.Scan(&spent.Currency, &spent.Amount); err != nil {
+				rows.Close()
+				return fmt.Errorf("customer summary retrieval error: %w", checkPostgresError(err))
+			}
+			summary.TotalSpent = append(summary.TotalSpent, spent)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("customer summary retrieval error: %w", checkPostgresError(err))
+		}
+
+		rows, err = q.Query(ctx, `SELECT i.brand, count(DISTINCT i.order_uid), count(*)
+			FROM item i JOIN orders o ON o.order_uid = i.order_uid AND o.date_created = i.date_created
+			WHERE o.customer_id = $1 AND o.deleted_at IS NULL AND i.brand <> ''
+			GROUP BY 1 ORDER BY 2 DESC, 3 DESC, 1 LIMIT $2`, customerID, topBrands)
+		if err != nil {
+			return fmt.Errorf("customer brands retrieval error: %w", checkPostgresError(err))
+		}
+		defer rows.Close()
+		summary.FavoriteBrands = []models.BrandStat{}
+		for rows.Next() {
+			var brand models.BrandStat
+			if err := rows.Scan(&brand.Brand, &brand.Orders, &brand.Items); err != nil {
+				return fmt.Errorf("customer brands retrieval error: %w", checkPostgresError(err))
+			}
+			summary.FavoriteBrands = append(summary.FavoriteBrands, brand)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("customer brands retrieval error: %w", checkPostgresError(err))
+		}
+		return nil
+	})
+	return summary, err
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SoftDeleteOrder помечает заказ удаленным, после чего он не возвращается при чтении.
+// Если expectedVersion не 0, заказ удаляется, только если его версия совпадает.
+func (p *Postgres) SoftDeleteOrder(ctx context.Context, orderUID string, expectedVersion int64) error {
+	return p.withTx(ctx, func(tx pgx.Tx) error {
+		query := `UPDATE orders SET deleted_at = now(), version = version + 1
+			WHERE order_uid = $1 AND date_created = ` + partitionKey("$1") + ` AND deleted_at IS NULL
+				AND ($2 = 0 OR version = $2)
+			RETURNING version, deleted_at`
+		var version int64
+		var deletedAt time.Time
+		err := tx.QueryRow(ctx, query, orderUID, expectedVersion).Scan(&version, &deletedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if err := versionConflict(ctx, tx, orderUID, expectedVersion); err != nil {
+				return err
+			}
+			return fmt.Errorf("order deletion error: %w", er.ErrOrderNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("order deletion error: %w", checkPostgresError(err))
+		}
+		return auditTx(ctx, tx, orderUID, models.AuditDelete, version,
+			map[string]any{"deleted_at": nil}, map[string]any{"deleted_at": deletedAt})
+	})
+}
+
+// RestoreOrder снимает пометку удаления с заказа
+func (p *Postgres) RestoreOrder(ctx context.Context, orderUID string) error {
+	return p.withTx(ctx, func(tx pgx.Tx) error {
+		// Подзапрос читает снимок до UPDATE, поэтому возвращает прежнее время удаления
+		query := `UPDATE orders o SET deleted_at = NULL, version = version + 1
+			WHERE order_uid = $1 AND date_created = ` + partitionKey("$1") + ` AND deleted_at IS NOT NULL
+			RETURNING version, (SELECT deleted_at FROM orders WHERE order_uid = o.order_uid AND date_created = o.date_created)`
+		var version int64
+		var deletedAt time.Time
+		err := tx.QueryRow(ctx, query, orderUID).Scan(&version, &deletedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("order restore error: %w: no deleted order with this order_uid", er.ErrOrderNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("order restore error: %w", checkPostgresError(err))
+		}
+		return auditTx(ctx, tx, orderUID, models.AuditRestore, version,
+			map[string]any{"deleted_at": deletedAt}, map[string]any{"deleted_at": nil})
+	})
+}
+
+// ListExpiredOrders возвращает до limit самых старых заказов, созданных раньше before:
+// мягко удаленные, если deleted, иначе активные
+func (p *Postgres) ListExpiredOrders(ctx context.Context, before time.Time, deleted bool, limit int) ([]models.Order, error) {
+	return queryOrders(ctx, p.pool, `WHERE o.date_created < $1 AND (o.deleted_at IS NOT NULL) = $2
+		ORDER BY o.date_created, o.order_uid LIMIT $3`, before, deleted, limit)
+}
+
+// PurgeOrders безвозвратно удаляет заказы вместе с доставкой, платежом и исходными сообщениями.
+// Товары, поисковые документы и история статусов удаляются каскадно вместе с записью order_keys.
+// В журнал аудита попадает только факт удаления: данные заказа не копируются, чтобы не хранить их дольше срока.
+func (p *Postgres) PurgeOrders(ctx context.Context, orderUIDs []string) (int, error) {
+	var purged int
+	err := p.withTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `DELETE FROM orders WHERE order_uid = ANY($1) RETURNING order_uid, version, delivery_id, payment_id`, orderUIDs)
+		if err != nil {
+			return fmt.Errorf("order purge error: %w", checkPostgresError(err))
+		}
+		var purgedUIDs []string
+		var versions []int64
+		var deliveryIDs, paymentIDs []int
+		for rows.Next() {
+			var orderUID string
+			var version int64
+			var deliveryID, paymentID int
+			if err := rows.Scan(&orderUID, &version, &deliveryID, &paymentID); err != nil {
+				rows.Close()
+				return fmt.Errorf("order purge error: %w", checkPostgresError(err))
+			}
+			purgedUIDs = append(purgedUIDs, orderUID)
+			versions = append(versions, version)
+			deliveryIDs = append(deliveryIDs, deliveryID)
+			paymentIDs = append(paymentIDs, paymentID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("order purge error: %w", checkPostgresError(err))
+		}
+		purged = len(deliveryIDs)
+
+		if _, err := tx.Exec(ctx, `DELETE FROM order_keys WHERE order_uid = ANY($1)`, orderUIDs); err != nil {
+			return fmt.Errorf("order purge error: %w", checkPostgresError(err))
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM delivery WHERE id = ANY($1)`, deliveryIDs); err != nil {
+			return fmt.Errorf("delivery purge error: %w", checkPostgresError(err))
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM payment WHERE id = ANY($1)`, paymentIDs); err != nil {
+			return fmt.Errorf("payment purge error: %w", checkPostgresError(err))
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM order_raw WHERE order_uid = ANY($1)`, orderUIDs); err != nil {
+			return fmt.Errorf("raw order purge error: %w", checkPostgresError(err))
+		}
+
+		source := models.AuditSourceFromContext(ctx)
+		_, err = tx.Exec(ctx, `INSERT INTO order_audit (order_uid, action, source, actor, version)
+			SELECT order_uid, $3, $4, $5, version FROM unnest($1::varchar[], $2::bigint[]) AS t(order_uid, version)`,
+			purgedUIDs, versions, models.AuditPurge, source.Kind, source.Actor)
+		if err != nil {
+			return fmt.Errorf("audit record creation error: %w", checkPostgresError(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReserveIdempotencyKey занимает ключ идемпотентности. Если ключ уже занят и не старше ttl,
+// возвращает сохраненную запись и false. Ключ без ответа старше lease (обработка прервалась
+// падением процесса) занимается заново.
+func (p *Postgres) ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl, lease time.Duration) (models.IdempotencyKey, bool, error) {
+	query := `INSERT INTO idempotency_keys (client, key, request_hash) VALUES ($1, $2, $3)
+		ON CONFLICT (client, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status_code = 0, response = NULL, created_at = now()
+			WHERE idempotency_keys.created_at < now() - $4::interval
+				OR (idempotency_keys.status_code = 0 AND idempotency_keys.created_at < now() - $5::interval)
+		RETURNING created_at`
+	err := p.pool.QueryRow(ctx, query, key.Client, key.Key, key.RequestHash, ttl, lease).Scan(&key.CreatedAt)
+	if err == nil {
+		return key, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return models.IdempotencyKey{}, false, fmt.Errorf("idempotency key reservation error: %w", checkPostgresError(err))
+	}
+
+	existing := models.IdempotencyKey{Client: key.Client, Key: key.Key}
+	err = p.pool.QueryRow(ctx, `SELECT request_hash, status_code, response, created_at FROM idempotency_keys
+		WHERE client = $1 AND key = $2`, key.Client, key.Key).
+		Scan(&existing.RequestHash, &existing.StatusCode, &existing.Response, &existing.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Ключ освободили между запросами: считаем, что он еще обрабатывается
+		existing.RequestHash = key.RequestHash
+		return existing, false, nil
+	}
+	if err != nil {
+		return models.IdempotencyKey{}, false, fmt.Errorf("idempotency key retrieval error: %w", checkPostgresError(err))
+	}
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности
+func (p *Postgres) CompleteIdempotencyKey(ctx context.Context, client, key string, statusCode int, response []byte) error {
+	_, err := p.pool.Exec(ctx, `UPDATE idempotency_keys SET status_code = $3, response = $4
+		WHERE client = $1 AND key = $2`, client, key, statusCode, response)
+	if err != nil {
+		return fmt.Errorf("idempotency key saving error: %w", checkPostgresError(err))
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ, чтобы запрос можно было повторить
+func (p *Postgres) ReleaseIdempotencyKey(ctx context.Context, client, key string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE client = $1 AND key = $2`, client, key)
+	if err != nil {
+		return fmt.Errorf("idempotency key release error: %w", checkPostgresError(err))
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys удаляет ключи старше ttl
+func (p *Postgres) PurgeIdempotencyKeys(ctx context.Context, ttl time.Duration) (int, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE created_at < now() - $1::interval`, ttl)
+	if err != nil {
+		return 0, fmt.Errorf("idempotency key purge error: %w", checkPostgresError(err))
+	}
+	return int(tag.RowsAffected()), nil
+}
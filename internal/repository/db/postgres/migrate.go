@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/migrations"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// migrationLockKey - ключ advisory lock, чтобы реплики не применяли миграции одновременно
+const migrationLockKey int64 = 0x6c305f6d696772 // "l0_migr"
+
+// Таблица версий совместима с golang-migrate
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT NOT NULL PRIMARY KEY,
+	dirty BOOLEAN NOT NULL
+)`
+
+// Migrator применяет встроенные миграции схемы
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []migrations.Migration
+}
+
+// Migrator возвращает мигратор для встроенных миграций
+func (p *Postgres) Migrator() (*Migrator, error) {
+	all, err := migrations.All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: p.pool, migrations: all}, nil
+}
+
+// Close закрывает пул соединений. Нужен только мигратору, созданному отдельно от хранилища.
+func (m *Migrator) Close() {
+	m.pool.Close()
+}
+
+// Latest возвращает последнюю известную версию схемы
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version возвращает текущую версию схемы БД и признак незавершенной миграции
+func (m *Migrator) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	err = m.pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == nil {
+		return version, dirty, nil
+	}
+
+	// Таблицы версий еще нет или она пуста — схема не инициализирована
+	var pgerr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgerr) && pgerr.Code == "42P01") {
+		return 0, false, nil
+	}
+	return 0, false, fmt.Errorf("schema version query error: %w", checkPostgresError(err))
+}
+
+// CheckVersion проверяет, что схема БД не старее версии, ожидаемой бинарником
+func (m *Migrator) CheckVersion(ctx context.Context) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database schema version %d is dirty, fix it manually and run `l0 migrate`", version)
+	}
+	if version < m.Latest() {
+		return fmt.Errorf("database schema version %d is older than required %d, run `l0 migrate up`", version, m.Latest())
+	}
+	if version > m.Latest() {
+		zap.S().Warnf("database schema version %d is newer than expected %d", version, m.Latest())
+	}
+	return nil
+}
+
+// Up применяет все неприменённые миграции
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down откатывает steps последних миграций
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.lockedVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		target := current
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			if m.migrations[i].Version <= target {
+				target = m.previousVersion(m.migrations[i].Version)
+				steps--
+			}
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// To приводит схему к указанной версии, применяя или откатывая миграции
+func (m *Migrator) To(ctx context.Context, target uint) error {
+	if target != 0 && m.find(target) < 0 {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.lockedVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// withLock выполняет fn на отдельном соединении под advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", checkPostgresError(err))
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", checkPostgresError(err))
+	}
+	defer func() {
+		// Снимаем блокировку, даже если контекст уже отменен
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			zap.S().Errorf("failed to release migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createVersionTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", checkPostgresError(err))
+	}
+	return fn(conn)
+}
+
+// lockedVersion читает версию схемы под блокировкой и отказывается работать с dirty схемой
+func (m *Migrator) lockedVersion(ctx context.Context, conn *pgxpool.Conn) (uint, error) {
+	var version uint
+	var dirty bool
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("schema version query error: %w", checkPostgresError(err))
+	}
+	if dirty {
+		return 0, fmt.Errorf("database schema version %d is dirty, fix it manually", version)
+	}
+	return version, nil
+}
+
+// migrate применяет (current < target) или откатывает (current > target) миграции по одной
+func (m *Migrator) migrate(ctx context.Context, conn *pgxpool.Conn, current, target uint) error {
+	if current == target {
+		zap.S().Infof("database schema is at version %d, nothing to do", current)
+		return nil
+	}
+
+	for _, mig := range m.migrations {
+		if current < target && mig.Version > current && mig.Version <= target {
+			if err := m.apply(ctx, conn, mig.Up, mig.Version); err != nil {
+				return fmt.Errorf("migration %d_%s up failed: %w", mig.Version, mig.Name, err)
+			}
+			zap.S().Infof("applied migration %d_%s", mig.Version, mig.Name)
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if current > target && mig.Version <= current && mig.Version > target {
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+			}
+			if err := m.apply(ctx, conn, mig.Down, m.previousVersion(mig.Version)); err != nil {
+				return fmt.Errorf("migration %d_%s down failed: %w", mig.Version, mig.Name, err)
+			}
+			zap.S().Infof("reverted migration %d_%s", mig.Version, mig.Name)
+		}
+	}
+
+	return nil
+}
+
+// apply выполняет скрипт миграции и записывает новую версию в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, script string, version uint) (err error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", checkPostgresError(err))
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				zap.S().Errorf("rollback error: %v", rbErr)
+			}
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, script); err != nil {
+		return checkPostgresError(err)
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return checkPostgresError(err)
+	}
+	if version > 0 {
+		if _, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, version); err != nil {
+			return checkPostgresError(err)
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", checkPostgresError(err))
+	}
+	return nil
+}
+
+// find возвращает индекс миграции с версией version или -1
+func (m *Migrator) find(version uint) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// previousVersion возвращает версию, предшествующую version (0, если это первая)
+func (m *Migrator) previousVersion(version uint) uint {
+	var prev uint
+	for _, mig := range m.migrations {
+		if mig.Version >= version {
+			break
+		}
+		prev = mig.Version
+	}
+	return prev
+}
@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// partitionedTables - секционированные по месяцам date_created таблицы.
+// Товары идут первыми, чтобы их секции отсоединялись раньше секций заказов.
+var partitionedTables = []string{"item", "orders"}
+
+// partitionLayout - формат месяца в имени секции: orders_p202401
+const partitionLayout = "200601"
+
+// partitionName возвращает имя месячной секции таблицы
+func partitionName(table string, month time.Time) string {
+	return table + "_p" + month.Format(partitionLayout)
+}
+
+// monthStart возвращает начало месяца t
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// CreatePartitions создает недостающие месячные секции orders и item для месяцев от from до to включительно.
+// Строки этого месяца, уже попавшие в секцию по умолчанию (например, заказы с датой из будущего),
+// переносятся в новую секцию в той же транзакции. Ошибка по одной секции не останавливает создание
+// остальных. Возвращает имена созданных секций и объединенную ошибку.
+func (p *Postgres) CreatePartitions(ctx context.Context, from, to time.Time) ([]string, error) {
+	var created []string
+	var errs []error
+	for month := monthStart(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		for _, table := range partitionedTables {
+			name := partitionName(table, month)
+			ok, err := p.createPartition(ctx, table, name, month)
+			if err != nil {
+				zap.S().Errorf("failed to create partition %s: %v", name, err)
+				errs = append(errs, err)
+				continue
+			}
+			if ok {
+				created = append(created, name)
+			}
+		}
+	}
+	return created, errors.Join(errs...)
+}
+
+// createPartition создает секцию name таблицы table для месяца month, если ее еще нет,
+// и переносит в нее строки этого месяца из секции по умолчанию
+func (p *Postgres) createPartition(ctx context.Context, table, name string, month time.Time) (bool, error) {
+	var exists bool
+	if err := p.pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return false, fmt.Errorf("partition lookup error: %w", checkPostgresError(err))
+	}
+	if exists {
+		return false, nil
+	}
+
+	from, to := month, month.AddDate(0, 1, 0)
+	parent := pgx.Identifier{table}.Sanitize()
+	err := p.withTx(ctx, func(tx pgx.Tx) error {
+		// Секцию нельзя создать, пока в секции по умолчанию есть строки ее диапазона
+		query := fmt.Sprintf(`CREATE TEMP TABLE partition_rows ON COMMIT DROP AS
+			WITH moved AS (DELETE FROM %s WHERE date_created >= $1 AND date_created < $2 RETURNING *)
+			SELECT * FROM moved`, pgx.Identifier{table + "_default"}.Sanitize())
+		if _, err := tx.Exec(ctx, query, from, to); err != nil {
+			return fmt.Errorf("partition %s default rows error: %w", name, checkPostgresError(err))
+		}
+
+		query = fmt.Sprintf(`CREATE TABLE %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+			pgx.Identifier{name}.Sanitize(), parent, from.Format(time.DateOnly), to.Format(time.DateOnly))
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("partition %s creation error: %w", name, checkPostgresError(err))
+		}
+
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`INSERT INTO %s SELECT * FROM partition_rows`, parent))
+		if err != nil {
+			return fmt.Errorf("partition %s rows move error: %w", name, checkPostgresError(err))
+		}
+		if moved := tag.RowsAffected(); moved > 0 {
+			zap.S().Infof("moved %d rows from %s_default to partition %s", moved, table, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DetachPartitions отсоединяет месячные секции orders и item, целиком лежащие раньше before.
+// Отсоединенные секции остаются отдельными таблицами, а доставка, оплата, история статусов
+// и исходные сообщения их заказов сохраняются рядом в order_details_pYYYYMM, после чего удаляются
+// из общих таблиц вместе с записями order_keys. Все это выполняется одной транзакцией на месяц,
+// поэтому заказы отсоединенной секции не мешают повторному приему того же order_uid.
+// Возвращает имена отсоединенных секций.
+func (p *Postgres) DetachPartitions(ctx context.Context, before time.Time) ([]string, error) {
+	names, err := p.listPartitions(ctx, "orders")
+	if err != nil {
+		return nil, err
+	}
+
+	var detached []string
+	for _, name := range names {
+		month, err := time.Parse(partitionLayout, name[len("orders")+2:])
+		if err != nil || month.AddDate(0, 1, 0).After(before) {
+			continue
+		}
+		if err := p.withTx(ctx, func(tx pgx.Tx) error {
+			return detachMonthTx(ctx, tx, month)
+		}); err != nil {
+			return detached, err
+		}
+		detached = append(detached, partitionName("item", month), partitionName("orders", month))
+	}
+	return detached, nil
+}
+
+// detachMonthTx отсоединяет секции месяца month и выносит зависимые строки их заказов
+func detachMonthTx(ctx context.Context, tx pgx.Tx, month time.Time) error {
+	orders := pgx.Identifier{partitionName("orders", month)}.Sanitize()
+	details := pgx.Identifier{"order_details_p" + month.Format(partitionLayout)}.Sanitize()
+
+	// Сначала сохраняем данные, которые иначе останутся только в общих таблицах
+	query := fmt.Sprintf(`CREATE TABLE %s AS
+		SELECT o.order_uid, to_jsonb(d) - 'id' AS delivery, to_jsonb(pay) - 'id' AS payment,
+			(SELECT jsonb_agg(to_jsonb(h) - 'id' ORDER BY h.id) FROM order_status_history h WHERE h.order_uid = o.order_uid) AS status_history,
+			(SELECT jsonb_agg(to_jsonb(r) - 'id' ORDER BY r.id) FROM order_raw r WHERE r.order_uid = o.order_uid) AS raw_orders
+		FROM %s o
+		JOIN delivery d ON d.id = o.delivery_id
+		JOIN payment pay ON pay.id = o.payment_id`, details, orders)
+	if _, err := tx.Exec(ctx, query); err != nil {
+		return fmt.Errorf("partition details error: %w", checkPostgresError(err))
+	}
+
+	for _, table := range partitionedTables {
+		name := partitionName(table, month)
+		query := fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, pgx.Identifier{table}.Sanitize(), pgx.Identifier{name}.Sanitize())
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("partition %s detach error: %w", name, checkPostgresError(err))
+		}
+		// Внешние ключи с ON DELETE CASCADE удалили бы строки отсоединенной секции вместе с order_keys
+		if err := dropForeignKeysTx(ctx, tx, name); err != nil {
+			return err
+		}
+	}
+
+	query = fmt.Sprintf(`WITH keys AS (
+			DELETE FROM order_keys WHERE order_uid IN (SELECT order_uid FROM %s) RETURNING order_uid
+		)
+		DELETE FROM order_raw WHERE order_uid IN (SELECT order_uid FROM keys)`, orders)
+	if _, err := tx.Exec(ctx, query); err != nil {
+		return fmt.Errorf("partition orders purge error: %w", checkPostgresError(err))
+	}
+	query = fmt.Sprintf(`DELETE FROM delivery WHERE id IN (SELECT delivery_id FROM %s)`, orders)
+	if _, err := tx.Exec(ctx, query); err != nil {
+		return fmt.Errorf("delivery purge error: %w", checkPostgresError(err))
+	}
+	query = fmt.Sprintf(`DELETE FROM payment WHERE id IN (SELECT payment_id FROM %s)`, orders)
+	if _, err := tx.Exec(ctx, query); err != nil {
+		return fmt.Errorf("payment purge error: %w", checkPostgresError(err))
+	}
+
+	source := models.AuditSourceFromContext(ctx)
+	query = fmt.Sprintf(`INSERT INTO order_audit (order_uid, action, source, actor, version)
+		SELECT order_uid, $1, $2, $3, version FROM %s`, orders)
+	if _, err := tx.Exec(ctx, query, models.AuditPurge, source.Kind, source.Actor); err != nil {
+		return fmt.Errorf("audit record creation error: %w", checkPostgresError(err))
+	}
+	return nil
+}
+
+// dropForeignKeysTx удаляет внешние ключи таблицы name
+func dropForeignKeysTx(ctx context.Context, tx pgx.Tx, name string) error {
+	rows, err := tx.Query(ctx, `SELECT conname FROM pg_constraint WHERE conrelid = to_regclass($1) AND contype = 'f'`, name)
+	if err != nil {
+		return fmt.Errorf("partition %s constraint query error: %w", name, checkPostgresError(err))
+	}
+	constraints, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("partition %s constraint query error: %w", name, checkPostgresError(err))
+	}
+	for _, constraint := range constraints {
+		query := fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT %s`, pgx.Identifier{name}.Sanitize(), pgx.Identifier{constraint}.Sanitize())
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("partition %s constraint drop error: %w", name, checkPostgresError(err))
+		}
+	}
+	return nil
+}
+
+// listPartitions возвращает имена месячных секций таблицы
+func (p *Postgres) listPartitions(ctx context.Context, table string) ([]string, error) {
+	query := `SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass($1) AND c.relname LIKE $1 || '\_p%'
+		ORDER BY c.relname`
+	rows, err := p.pool.Query(ctx, query, table)
+	if err != nil {
+		return nil, fmt.Errorf("partition query error: %w", checkPostgresError(err))
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("partition scanning error: %w", checkPostgresError(err))
+		}
+		names = append(names, name)
+	}
+
+	// Проверка на ошибки итерации
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("partition iteration error: %w", checkPostgresError(err))
+	}
+
+	return names, nil
+}
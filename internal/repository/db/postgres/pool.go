@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolConfig - настройки пула соединений. Нулевые значения оставляют настройки pgxpool по умолчанию.
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+	// StatementTimeout - statement_timeout каждого соединения
+	StatementTimeout time.Duration
+}
+
+// newPool создает пул с настройками cfg. Соединения устанавливаются лениво.
+func newPool(connString string, cfg PoolConfig) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection string: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.ConnectTimeout > 0 {
+		poolCfg.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	if cfg.StatementTimeout > 0 {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
+	}
+	return pool, nil
+}
+
+// PoolStats - состояние пула соединений для метрик и проверки здоровья
+type PoolStats struct {
+	Role                 string `json:"role"` // primary или replica
+	Host                 string `json:"host"`
+	Healthy              bool   `json:"healthy"`
+	TotalConns           int32  `json:"total_conns"`
+	AcquiredConns        int32  `json:"acquired_conns"`
+	IdleConns            int32  `json:"idle_conns"`
+	MaxConns             int32  `json:"max_conns"`
+	AcquireCount         int64  `json:"acquire_count"`
+	EmptyAcquireCount    int64  `json:"empty_acquire_count"`    // получения соединения с ожиданием
+	AcquireDurationMs    int64  `json:"acquire_duration_ms"`    // суммарное время получения соединений
+	EmptyAcquireWaitMs   int64  `json:"empty_acquire_wait_ms"`  // суммарное время ожидания свободного соединения
+	CanceledAcquireCount int64  `json:"canceled_acquire_count"` // получения, прерванные отменой контекста
+}
+
+// poolStats снимает статистику пула
+func poolStats(role string, pool *pgxpool.Pool, healthy bool) PoolStats {
+	stat := pool.Stat()
+	return PoolStats{
+		Role:                 role,
+		Host:                 pool.Config().ConnConfig.Host,
+		Healthy:              healthy,
+		TotalConns:           stat.TotalConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		AcquireDurationMs:    stat.AcquireDuration().Milliseconds(),
+		EmptyAcquireWaitMs:   stat.EmptyAcquireWaitTime().Milliseconds(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+	}
+}
+
+// Ping проверяет соединение с основной БД
+func (p *Postgres) Ping(ctx context.Context) error {
+	if err := p.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("database ping error: %w", checkPostgresError(err))
+	}
+	return nil
+}
+
+// Health проверяет основную БД и реплики и возвращает статистику их пулов.
+// Ошибка возвращается, только если недоступна основная БД.
+func (p *Postgres) Health(ctx context.Context) ([]PoolStats, error) {
+	pingErr := p.Ping(ctx)
+	stats := []PoolStats{poolStats("primary", p.pool, pingErr == nil)}
+	for _, r := range p.replicas {
+		r.check(ctx, p.replicaCfg)
+		stats = append(stats, poolStats("replica", r.pool, r.healthy.Load()))
+	}
+	return stats, pingErr
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"l0/internal/models"
 	"l0/pkg/er"
+	"slices"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -13,9 +16,13 @@ import (
 	"go.uber.org/zap"
 )
 
-// Postgres - структура для работы с БД
+// Postgres - структура для работы с БД. Записи идут в основную БД (pool),
+// чтения заказов и поиск - в реплики, если они настроены.
 type Postgres struct {
-	pool *pgxpool.Pool
+	pool       *pgxpool.Pool
+	replicas   []*replica
+	replicaCfg ReplicaConfig
+	next       atomic.Uint32 // счетчик для выбора реплики по кругу
 }
 
 // checkPostgresError проверяет специфичные ошибки PostgreSQL
@@ -60,107 +67,157 @@ func checkPostgresError(err error) error {
 	return fmt.Errorf("%w: %v", er.ErrDatabaseError, err)
 }
 
-func NewPostgres(connString string) (*Postgres, error) {
-	pool, err := pgxpool.New(context.Background(), connString)
+// NewPostgres создает пулы основной БД и реплик. Соединение не проверяется, для этого есть Ping.
+func NewPostgres(connString string, poolCfg PoolConfig, replicaCfg ReplicaConfig) (*Postgres, error) {
+	pool, err := newPool(connString, poolCfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
+		return nil, err
 	}
-	return &Postgres{pool: pool}, nil
+	replicas, err := newReplicas(replicaCfg, poolCfg)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &Postgres{pool: pool, replicas: replicas, replicaCfg: replicaCfg}, nil
 }
 
-// Публичные CRUD для Order
-func (p *Postgres) CreateOrder(ctx context.Context, order models.Order) error {
-	if order.OrderUID == "" {
-		return fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
-	}
+// Close закрывает пулы соединений основной БД и реплик
+func (p *Postgres) Close() {
+	closeReplicas(p.replicas)
+	p.pool.Close()
+}
 
+// withTx выполняет fn в транзакции: фиксирует ее при успехе и откатывает при ошибке
+func (p *Postgres) withTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
 	tx, err := p.pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", checkPostgresError(err))
 	}
 
-	defer func() {
-		if err != nil {
-			if rbErr := tx.Rollback(ctx); rbErr != nil {
-				zap.S().Errorf("rollback error: %v\n", rbErr)
-			}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			zap.S().Errorf("rollback error: %v", rbErr)
 		}
-	}()
+		return err
+	}
 
-	deliveryID, err := p.createDeliveryTx(ctx, tx, order.Delivery)
-	if err != nil {
-		return fmt.Errorf("delivery creation error: %w", checkPostgresError(err))
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", checkPostgresError(err))
 	}
+	return nil
+}
 
-	paymentID, err := p.createPaymentTx(ctx, tx, order.Payment)
-	if err != nil {
-		return fmt.Errorf("payment creation error: %w", checkPostgresError(err))
+// Публичные CRUD для Order
+func (p *Postgres) CreateOrder(ctx context.Context, order models.Order) error {
+	if err := order.Validate(); err != nil {
+		return err
 	}
 
-	query := `INSERT INTO orders (
-		order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
-	) VALUES (
-		$1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13
-	)`
+	return p.withTx(ctx, func(tx pgx.Tx) error {
+		return p.createOrderTx(ctx, tx, order)
+	})
+}
 
-	_, err = tx.Exec(ctx, query,
-		order.OrderUID, order.TrackNumber, order.Entry, deliveryID, paymentID, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard,
-	)
-	if err != nil {
-		return fmt.Errorf("order creation error: %w", checkPostgresError(err))
+// CreateOrderWithRaw создает заказ и сохраняет его исходное сообщение в одной транзакции
+func (p *Postgres) CreateOrderWithRaw(ctx context.Context, order models.Order, raw models.RawOrder) error {
+	if err := order.Validate(); err != nil {
+		return err
 	}
 
-	for i, item := range order.Items {
-		_, err := p.createItemTx(ctx, tx, item, order.OrderUID)
-		if err != nil {
-			return fmt.Errorf("item %d creation error: %w", i+1, checkPostgresError(err))
+	return p.withTx(ctx, func(tx pgx.Tx) error {
+		if err := p.createOrderTx(ctx, tx, order); err != nil {
+			return err
 		}
-	}
+		return insertRawOrderTx(ctx, tx, raw)
+	})
+}
 
-	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", checkPostgresError(err))
+// ReplaceOrder создает заказ или полностью заменяет данные существующего.
+// Строки orders, delivery и payment обновляются на месте, поэтому связанные с заказом
+// записи других таблиц сохраняются; товары пересоздаются. Статус заказа не меняется —
+// для этого есть AppendStatusChange.
+func (p *Postgres) ReplaceOrder(ctx context.Context, order models.Order) error {
+	if err := order.Validate(); err != nil {
+		return err
 	}
 
-	return nil
+	return p.withTx(ctx, func(tx pgx.Tx) error {
+		locked, err := lockOrderTx(ctx, tx, order.OrderUID)
+		if errors.Is(err, er.ErrOrderNotFound) {
+			return p.createOrderTx(ctx, tx, order)
+		}
+		if err != nil {
+			return err
+		}
+		return p.updateOrderTx(ctx, tx, order, locked)
+	})
 }
 
 func (p *Postgres) GetOrder(ctx context.Context, orderUID string) (models.Order, error) {
 	var order models.Order
-	var deliveryID, paymentID int
 
 	// Валидация входных данных
 	if orderUID == "" {
 		return order, fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
 	}
 
-	query := `SELECT order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard FROM orders WHERE order_uid = $1`
-	err := p.pool.QueryRow(ctx, query, orderUID).
-		Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &deliveryID, &paymentID, &order.Locale, &order.InternalSignature, &order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard)
-	if err != nil {
-		return order, fmt.Errorf("order retrieval error: %w", checkPostgresError(err))
-	}
+	// Заказ, доставка, платеж и товары загружаются одним запросом из секций заказа
+	query := orderSelect + `,
+		COALESCE((SELECT json_agg(` + itemJSON + ` ORDER BY i.id) FROM item i
+			WHERE i.order_uid = o.order_uid AND i.date_created = o.date_created), '[]')
+	` + orderFrom + ` WHERE o.order_uid = $1 AND o.date_created = ` + partitionKey("$1") + ` AND ` + activeOrder
+	err := p.read(ctx, func(q querier) error {
+		order = models.Order{}
+		if err := scanOrder(q.QueryRow(ctx, query, orderUID), &order, &order.Items); err != nil {
+			return fmt.Errorf("order retrieval error: %w", checkPostgresError(err))
+		}
+		return nil
+	})
+	return order, err
+}
 
-	order.Delivery, err = p.getDelivery(ctx, deliveryID)
-	if err != nil {
-		return order, fmt.Errorf("delivery data retrieval error: %w", checkPostgresError(err))
-	}
+// GetOrdersByUIDs возвращает найденные заказы из списка одним запросом (товары - вторым).
+// Отсутствующие и удаленные заказы пропускаются, порядок результата не определен.
+func (p *Postgres) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) ([]models.Order, error) {
+	// Условие на date_created из order_keys ограничивает чтение секциями запрошенных заказов
+	return p.readOrders(ctx, `WHERE o.order_uid = ANY($1)
+		AND o.date_created IN (SELECT date_created FROM order_keys WHERE order_uid = ANY($1)) AND `+activeOrder, orderUIDs)
+}
 
-	order.Payment, err = p.getPayment(ctx, paymentID)
-	if err != nil {
-		return order, fmt.Errorf("payment data retrieval error: %w", checkPostgresError(err))
-	}
+func (p *Postgres) GetOrders(ctx context.Context) ([]models.Order, error) {
+	return p.readOrders(ctx, "WHERE "+activeOrder)
+}
 
-	order.Items, err = p.getItemsByOrderUID(ctx, orderUID)
-	if err != nil {
-		return order, fmt.Errorf("order items retrieval error: %w", checkPostgresError(err))
-	}
+// ListOrders возвращает до limit заказов после курсора в порядке (date_created, order_uid)
+func (p *Postgres) ListOrders(ctx context.Context, filter models.OrderFilter, after *models.Cursor, limit int) ([]models.Order, error) {
+	var w whereBuilder
+	w.add(activeOrder)
+	applyOrderFilter(&w, filter)
+	if after != nil {
+		// Отдельное условие на date_created отсекает секции до курсора
+		w.add("o.date_created >= " + w.arg(after.DateCreated))
+		w.add("(o.date_created, o.order_uid) > (" + w.arg(after.DateCreated) + ", " + w.arg(after.OrderUID) + ")")
+	}
+	clause := w.sql() + " ORDER BY o.date_created, o.order_uid LIMIT " + w.arg(limit)
+	return p.readOrders(ctx, clause, w.args...)
+}
 
-	return order, nil
+// readOrders выполняет queryOrders на реплике с переходом на основную БД
+func (p *Postgres) readOrders(ctx context.Context, clause string, args ...any) ([]models.Order, error) {
+	var orders []models.Order
+	err := p.read(ctx, func(q querier) error {
+		var err error
+		orders, err = queryOrders(ctx, q, clause, args...)
+		return err
+	})
+	return orders, err
 }
 
-func (p *Postgres) GetOrders(ctx context.Context) ([]models.Order, error) {
-	query := `SELECT order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard FROM orders`
-	rows, err := p.pool.Query(ctx, query)
+// queryOrders загружает заказы с доставкой и платежом одним запросом,
+// а товары всех найденных заказов — вторым запросом.
+// clause - условие, сортировка и ограничение выборки.
+func queryOrders(ctx context.Context, q querier, clause string, args ...any) ([]models.Order, error) {
+	rows, err := q.Query(ctx, orderSelect+" "+orderFrom+" "+clause, args...)
 	if err != nil {
 		return nil, fmt.Errorf("order query error: %w", checkPostgresError(err))
 	}
@@ -169,156 +226,286 @@ func (p *Postgres) GetOrders(ctx context.Context) ([]models.Order, error) {
 	var orders []models.Order
 	for rows.Next() {
 		var order models.Order
-		var deliveryID, paymentID int
-		err := rows.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &deliveryID, &paymentID, &order.Locale, &order.InternalSignature, &order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard)
-		if err != nil {
+		if err := scanOrder(rows, &order); err != nil {
 			return nil, fmt.Errorf("order scanning error: %w", checkPostgresError(err))
 		}
+		orders = append(orders, order)
+	}
 
-		order.Delivery, err = p.getDelivery(ctx, deliveryID)
-		if err != nil {
-			return nil, fmt.Errorf("delivery data retrieval error for order %s: %w", order.OrderUID, checkPostgresError(err))
-		}
+	// Проверка на ошибки итерации
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("order iteration error: %w", checkPostgresError(err))
+	}
 
-		order.Payment, err = p.getPayment(ctx, paymentID)
-		if err != nil {
-			return nil, fmt.Errorf("payment data retrieval error for order %s: %w", order.OrderUID, checkPostgresError(err))
+	if err := loadItems(ctx, q, orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// loadItems загружает товары для набора заказов одним запросом
+func loadItems(ctx context.Context, q querier, orders []models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(orders))
+	orderUIDs := make([]string, len(orders))
+	from, to := orders[0].DateCreated, orders[0].DateCreated
+	for i, order := range orders {
+		index[order.OrderUID] = i
+		orderUIDs[i] = order.OrderUID
+		if order.DateCreated.Before(from) {
+			from = order.DateCreated
+		}
+		if order.DateCreated.After(to) {
+			to = order.DateCreated
 		}
+	}
 
-		order.Items, err = p.getItemsByOrderUID(ctx, order.OrderUID)
+	// Диапазон date_created ограничивает чтение секциями найденных заказов
+	query := `SELECT order_uid, ` + itemColumns + ` FROM item
+		WHERE order_uid = ANY($1) AND date_created BETWEEN $2 AND $3 ORDER BY id`
+	rows, err := q.Query(ctx, query, orderUIDs, from, to)
+	if err != nil {
+		return fmt.Errorf("item query error: %w", checkPostgresError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderUID string
+		var item models.Item
+		err := rows.Scan(&orderUID, &item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status)
 		if err != nil {
-			return nil, fmt.Errorf("items retrieval error for order %s: %w", order.OrderUID, checkPostgresError(err))
+			return fmt.Errorf("item scanning error: %w", checkPostgresError(err))
+		}
+		if i, ok := index[orderUID]; ok {
+			orders[i].Items = append(orders[i].Items, item)
 		}
-
-		orders = append(orders, order)
 	}
 
 	// Проверка на ошибки итерации
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("order iteration error: %w", checkPostgresError(err))
+		return fmt.Errorf("item iteration error: %w", checkPostgresError(err))
 	}
 
-	return orders, nil
+	return nil
 }
 
-// Методы для работы с транзакциями
-func (p *Postgres) createDeliveryTx(ctx context.Context, tx pgx.Tx, d models.Delivery) (int, error) {
-	// Валидация данных доставки
-	if d.Name == "" {
-		return 0, fmt.Errorf("%w: recipient name is required", er.ErrInvalidData)
+// SearchOrdersFullText ищет заказы по товарам и адресу доставки с ранжированием.
+// Запрос разбирается как websearch: слова объединяются через И, поддерживаются "фразы" и -исключения.
+func (p *Postgres) SearchOrdersFullText(ctx context.Context, text string, limit int) ([]models.SearchHit, error) {
+	var hits []models.SearchHit
+	err := p.read(ctx, func(q querier) error {
+		var err error
+		hits, err = searchOrdersFullText(ctx, q, text, limit)
+		return err
+	})
+	return hits, err
+}
+
+// searchOrdersFullText выполняет полнотекстовый поиск через q
+func searchOrdersFullText(ctx context.Context, q querier, text string, limit int) ([]models.SearchHit, error) {
+	query := `SELECT s.order_uid, ts_rank(s.search_vector, q) AS rank,
+			ts_headline('simple', s.document, q, format('StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=15, MinWords=5', chr(2), chr(3)))
+		FROM order_search s
+		JOIN orders o ON o.order_uid = s.order_uid, websearch_to_tsquery('simple', $1) q
+		WHERE s.search_vector @@ q AND ` + activeOrder + `
+		ORDER BY rank DESC, s.order_uid
+		LIMIT $2`
+	rows, err := q.Query(ctx, query, text, limit)
+	if err != nil {
+		return nil, fmt.Errorf("full-text search error: %w", checkPostgresError(err))
 	}
-	if d.Phone == "" {
-		return 0, fmt.Errorf("%w: recipient phone is required", er.ErrInvalidData)
+	defer rows.Close()
+
+	var hits []models.SearchHit
+	for rows.Next() {
+		var hit models.SearchHit
+		if err := rows.Scan(&hit.OrderUID, &hit.Rank, &hit.Snippet); err != nil {
+			return nil, fmt.Errorf("search hit scanning error: %w", checkPostgresError(err))
+		}
+		hits = append(hits, hit)
 	}
 
-	query := `INSERT INTO delivery (name, phone, zip, city, address, region, email) VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`
-	var id int
-	err := tx.QueryRow(ctx, query,
-		d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email).Scan(&id)
-	if err != nil {
-		return 0, checkPostgresError(err)
+	// Проверка на ошибки итерации
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("search hit iteration error: %w", checkPostgresError(err))
 	}
-	return id, nil
+
+	return hits, nil
 }
 
-func (p *Postgres) getDelivery(ctx context.Context, id int) (models.Delivery, error) {
-	if id <= 0 {
-		return models.Delivery{}, fmt.Errorf("%w: invalid delivery ID", er.ErrInvalidData)
-	}
+// Методы для работы с транзакциями
 
-	query := `SELECT name, phone, zip, city, address, region, email FROM delivery WHERE id=$1`
-	var d models.Delivery
-	err := p.pool.QueryRow(ctx, query, id).
-		Scan(&d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email)
+// createOrderTx сохраняет заказ со всеми связанными данными и пишет в журнал аудита запись о создании
+func (p *Postgres) createOrderTx(ctx context.Context, tx pgx.Tx, order models.Order) error {
+	order, err := p.insertOrderTx(ctx, tx, order)
+	if err != nil {
+		return err
+	}
+	order.Version = 1
+	_, fields, err := models.AuditDiff(nil, &order)
 	if err != nil {
-		return d, checkPostgresError(err)
+		return err
 	}
-	return d, nil
+	return auditTx(ctx, tx, order.OrderUID, models.AuditCreate, order.Version, nil, fields)
 }
 
-func (p *Postgres) createPaymentTx(ctx context.Context, tx pgx.Tx, pay models.Payment) (int, error) {
-	// Валидация данных платежа
-	if pay.Transaction == "" {
-		return 0, fmt.Errorf("%w: transaction number is required", er.ErrInvalidData)
+// insertOrderTx сохраняет заказ со всеми связанными данными и начальной записью истории статусов
+// и возвращает сохраненный заказ с нормализованным статусом
+func (p *Postgres) insertOrderTx(ctx context.Context, tx pgx.Tx, order models.Order) (models.Order, error) {
+	order.Items = slices.Clone(order.Items)
+	order.NormalizeStatus()
+
+	deliveryID, err := p.createDeliveryTx(ctx, tx, order.Delivery)
+	if err != nil {
+		return order, fmt.Errorf("delivery creation error: %w", checkPostgresError(err))
 	}
-	if pay.Provider == "" {
-		return 0, fmt.Errorf("%w: payment provider is required", er.ErrInvalidData)
+
+	paymentID, err := p.createPaymentTx(ctx, tx, order.Payment)
+	if err != nil {
+		return order, fmt.Errorf("payment creation error: %w", checkPostgresError(err))
 	}
-	if pay.Amount <= 0 {
-		return 0, fmt.Errorf("%w: payment amount must be greater than zero", er.ErrInvalidData)
+
+	// Реестр ключей проверяет уникальность order_uid и track_number
+	_, err = tx.Exec(ctx, `INSERT INTO order_keys (order_uid, track_number, date_created) VALUES ($1, $2, $3)`,
+		order.OrderUID, order.TrackNumber, order.DateCreated)
+	if err != nil {
+		return order, fmt.Errorf("order creation error: %w", checkPostgresError(err))
 	}
 
-	query := `INSERT INTO payment (transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id`
-	var id int
-	err := tx.QueryRow(ctx, query,
-		pay.Transaction, pay.RequestID, pay.Currency, pay.Provider, pay.Amount, pay.PaymentDt, pay.Bank, pay.DeliveryCost, pay.GoodsTotal, pay.CustomFee).Scan(&id)
+	query := `INSERT INTO orders (
+		order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status, event_version
+	) VALUES (
+		$1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15
+	)`
+
+	_, err = tx.Exec(ctx, query,
+		order.OrderUID, order.TrackNumber, order.Entry, deliveryID, paymentID, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard, order.Status, order.EventVersion,
+	)
 	if err != nil {
-		return 0, checkPostgresError(err)
+		return order, fmt.Errorf("order creation error: %w", checkPostgresError(err))
 	}
-	return id, nil
+
+	// Начальная запись истории статусов
+	_, err = tx.Exec(ctx, `INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at) VALUES ($1, 0, $2, $3)`,
+		order.OrderUID, order.Status, order.DateCreated)
+	if err != nil {
+		return order, fmt.Errorf("status history creation error: %w", checkPostgresError(err))
+	}
+
+	if err := p.createItemsTx(ctx, tx, order, order.Status); err != nil {
+		return order, err
+	}
+	return order, nil
 }
 
-func (p *Postgres) getPayment(ctx context.Context, id int) (models.Payment, error) {
-	if id <= 0 {
-		return models.Payment{}, fmt.Errorf("%w: invalid payment ID", er.ErrInvalidData)
+// updateOrderTx перезаписывает заблокированный заказ, его доставку и платеж, пересоздает товары
+// и увеличивает версию заказа. В журнал аудита пишутся измененные поля до и после изменения.
+func (p *Postgres) updateOrderTx(ctx context.Context, tx pgx.Tx, order models.Order, locked lockedOrder) error {
+	before, err := loadOrderTx(ctx, tx, order.OrderUID)
+	if err != nil {
+		return err
 	}
 
-	query := `SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee FROM payment WHERE id=$1`
-	var pay models.Payment
-	err := p.pool.QueryRow(ctx, query, id).
-		Scan(&pay.Transaction, &pay.RequestID, &pay.Currency, &pay.Provider, &pay.Amount, &pay.PaymentDt, &pay.Bank, &pay.DeliveryCost, &pay.GoodsTotal, &pay.CustomFee)
+	d := order.Delivery
+	_, err = tx.Exec(ctx, `UPDATE delivery SET name=$2, phone=$3, zip=$4, city=$5, address=$6, region=$7, email=$8 WHERE id=$1`,
+		locked.deliveryID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email)
 	if err != nil {
-		return pay, checkPostgresError(err)
+		return fmt.Errorf("delivery update error: %w", checkPostgresError(err))
 	}
-	return pay, nil
-}
 
-func (p *Postgres) getItemsByOrderUID(ctx context.Context, orderUID string) ([]models.Item, error) {
-	if orderUID == "" {
-		return nil, fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	pay := order.Payment
+	_, err = tx.Exec(ctx, `UPDATE payment SET transaction=$2, request_id=$3, currency=$4, provider=$5, amount=$6, payment_dt=$7, bank=$8, delivery_cost=$9, goods_total=$10, custom_fee=$11 WHERE id=$1`,
+		locked.paymentID, pay.Transaction, pay.RequestID, pay.Currency, pay.Provider, pay.Amount, pay.PaymentDt, pay.Bank, pay.DeliveryCost, pay.GoodsTotal, pay.CustomFee)
+	if err != nil {
+		return fmt.Errorf("payment update error: %w", checkPostgresError(err))
 	}
 
-	query := `SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM item WHERE order_uid=$1`
-	rows, err := p.pool.Query(ctx, query, orderUID)
+	_, err = tx.Exec(ctx, `UPDATE order_keys SET track_number=$2, date_created=$3 WHERE order_uid=$1`,
+		order.OrderUID, order.TrackNumber, order.DateCreated)
 	if err != nil {
-		return nil, fmt.Errorf("item query error: %w", checkPostgresError(err))
+		return fmt.Errorf("order update error: %w", checkPostgresError(err))
 	}
-	defer rows.Close()
 
-	var items []models.Item
-	for rows.Next() {
-		var item models.Item
-		err := rows.Scan(&item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status)
-		if err != nil {
-			return nil, fmt.Errorf("item scanning error: %w", checkPostgresError(err))
-		}
-		items = append(items, item)
+	// При смене date_created Postgres переносит строку в другую секцию
+	query := `UPDATE orders SET track_number=$3, entry=$4, locale=$5, internal_signature=$6, customer_id=$7, delivery_service=$8, shardkey=$9, sm_id=$10, date_created=$11, oof_shard=$12,
+			version = version + 1, event_version = GREATEST(event_version, $13)
+		WHERE order_uid=$1 AND date_created=$2`
+	_, err = tx.Exec(ctx, query,
+		order.OrderUID, locked.dateCreated, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard, order.EventVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("order update error: %w", checkPostgresError(err))
 	}
 
-	// Проверка на ошибки итерации
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("item iteration error: %w", checkPostgresError(err))
+	if _, err = tx.Exec(ctx, `DELETE FROM item WHERE order_uid = $1 AND date_created = $2`, order.OrderUID, locked.dateCreated); err != nil {
+		return fmt.Errorf("item deletion error: %w", checkPostgresError(err))
 	}
 
-	return items, nil
+	// Статус заказа здесь не меняется, товары получают текущий
+	if err := p.createItemsTx(ctx, tx, order, before.Status); err != nil {
+		return err
+	}
+	after, err := loadOrderTx(ctx, tx, order.OrderUID)
+	if err != nil {
+		return err
+	}
+	beforeFields, afterFields, err := models.AuditDiff(&before, &after)
+	if err != nil {
+		return err
+	}
+	return auditTx(ctx, tx, order.OrderUID, models.AuditUpdate, after.Version, beforeFields, afterFields)
 }
 
-func (p *Postgres) createItemTx(ctx context.Context, tx pgx.Tx, item models.Item, orderUID string) (int, error) {
-	// Валидация данных товара
-	if item.Name == "" {
-		return 0, fmt.Errorf("%w: item name is required", er.ErrInvalidData)
+// createItemsTx сохраняет товары заказа со статусом status (статус товаров всегда совпадает
+// со статусом заказа) и пересобирает поисковый документ заказа
+func (p *Postgres) createItemsTx(ctx context.Context, tx pgx.Tx, order models.Order, status models.Status) error {
+	for i, item := range order.Items {
+		item.Status = status
+		if _, err := p.createItemTx(ctx, tx, item, order.OrderUID, order.DateCreated); err != nil {
+			return fmt.Errorf("item %d creation error: %w", i+1, checkPostgresError(err))
+		}
 	}
-	if item.Price <= 0 {
-		return 0, fmt.Errorf("%w: item price must be greater than zero", er.ErrInvalidData)
+
+	if _, err := tx.Exec(ctx, refreshSearchDocument, order.OrderUID); err != nil {
+		return fmt.Errorf("search document creation error: %w", checkPostgresError(err))
 	}
+	return nil
+}
+func (p *Postgres) createDeliveryTx(ctx context.Context, tx pgx.Tx, d models.Delivery) (int, error) {
+	query := `INSERT INTO delivery (name, phone, zip, city, address, region, email) VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`
+	var id int
+	err := tx.QueryRow(ctx, query,
+		d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email).Scan(&id)
+	if err != nil {
+		return 0, checkPostgresError(err)
+	}
+	return id, nil
+}
+
+func (p *Postgres) createPaymentTx(ctx context.Context, tx pgx.Tx, pay models.Payment) (int, error) {
+	query := `INSERT INTO payment (transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id`
+	var id int
+	err := tx.QueryRow(ctx, query,
+		pay.Transaction, pay.RequestID, pay.Currency, pay.Provider, pay.Amount, pay.PaymentDt, pay.Bank, pay.DeliveryCost, pay.GoodsTotal, pay.CustomFee).Scan(&id)
+	if err != nil {
+		return 0, checkPostgresError(err)
+	}
+	return id, nil
+}
+
+func (p *Postgres) createItemTx(ctx context.Context, tx pgx.Tx, item models.Item, orderUID string, dateCreated time.Time) (int, error) {
 	if orderUID == "" {
 		return 0, fmt.Errorf("%w: item order_uid is required", er.ErrInvalidData)
 	}
 
-	query := `INSERT INTO item (chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) RETURNING id`
+	query := `INSERT INTO item (chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid, date_created) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13) RETURNING id`
 	var id int
 	err := tx.QueryRow(ctx, query,
-		item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status, orderUID).Scan(&id)
+		item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status, orderUID, dateCreated).Scan(&id)
 	if err != nil {
 		return 0, checkPostgresError(err)
 	}
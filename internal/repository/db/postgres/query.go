@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"fmt"
+	"l0/internal/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// orderSelect - колонки заказа вместе с доставкой и платежом.
+// Порядок колонок соответствует scanOrder.
+const orderSelect = `SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status, o.version, o.event_version,
+		d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+		p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee`
+
+// orderFrom соединяет заказ с доставкой и платежом
+const orderFrom = `FROM orders o
+	JOIN delivery d ON d.id = o.delivery_id
+	JOIN payment p ON p.id = o.payment_id`
+
+// activeOrder исключает мягко удаленные заказы (алиас orders - o)
+const activeOrder = `o.deleted_at IS NULL`
+
+// partitionKey возвращает подзапрос date_created заказа из реестра order_keys.
+// Условие "date_created = partitionKey(...)" позволяет Postgres читать только секцию заказа.
+func partitionKey(orderUID string) string {
+	return "(SELECT date_created FROM order_keys WHERE order_uid = " + orderUID + ")"
+}
+
+// itemColumns - колонки товара в порядке полей models.Item
+const itemColumns = `chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status`
+
+// itemJSON собирает строку item (алиас i) в JSON с ключами models.Item
+const itemJSON = `json_build_object(
+		'chrt_id', i.chrt_id, 'track_number', i.track_number, 'price', i.price, 'rid', i.rid, 'name', i.name,
+		'sale', i.sale, 'size', i.size, 'total_price', i.total_price, 'nm_id', i.nm_id, 'brand', i.brand, 'status', i.status)`
+
+// scanOrder читает строку orderSelect, extra - дополнительные колонки после основных
+func scanOrder(row pgx.Row, order *models.Order, extra ...any) error {
+	dest := []any{
+		&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature, &order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard, &order.Status, &order.Version, &order.EventVersion,
+		&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
+		&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider, &order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank, &order.Payment.DeliveryCost, &order.Payment.GoodsTotal, &order.Payment.CustomFee,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// refreshSearchDocument пересобирает поисковый документ заказа $1.
+// Состав документа совпадает с миграцией 3_fulltext_search.
+const refreshSearchDocument = `INSERT INTO order_search (order_uid, document, search_vector)
+	SELECT o.order_uid,
+		concat_ws(' ', items.text, d.name, d.city, d.address),
+		setweight(to_tsvector('simple', coalesce(items.text, '')), 'A') ||
+		setweight(to_tsvector('simple', concat_ws(' ', d.name, d.city, d.address)), 'B')
+	FROM orders o
+	JOIN delivery d ON d.id = o.delivery_id
+	LEFT JOIN LATERAL (
+		SELECT string_agg(concat_ws(' ', i.brand, i.name), ' ' ORDER BY i.id) AS text
+		FROM item i
+		WHERE i.order_uid = o.order_uid AND i.date_created = o.date_created
+	) items ON true
+	WHERE o.order_uid = $1 AND o.date_created = (SELECT date_created FROM order_keys WHERE order_uid = $1)
+	ON CONFLICT (order_uid) DO UPDATE SET document = EXCLUDED.document, search_vector = EXCLUDED.search_vector`
+
+// whereBuilder собирает условие WHERE с позиционными параметрами
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+// arg добавляет параметр запроса и возвращает его плейсхолдер
+func (w *whereBuilder) arg(v any) string {
+	w.args = append(w.args, v)
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+// add добавляет условие, соединяемое через AND
+func (w *whereBuilder) add(cond string) {
+	w.conds = append(w.conds, cond)
+}
+
+// sql возвращает готовое условие WHERE или пустую строку
+func (w *whereBuilder) sql() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// applyOrderFilter добавляет условия фильтра заказов (алиасы orderFrom)
+func applyOrderFilter(w *whereBuilder, filter models.OrderFilter) {
+	if filter.TrackNumber != "" {
+		w.add("o.track_number = " + w.arg(filter.TrackNumber))
+	}
+	if filter.CustomerID != "" {
+		w.add("o.customer_id = " + w.arg(filter.CustomerID))
+	}
+	if filter.Phone != "" {
+		w.add("d.phone = " + w.arg(filter.Phone))
+	}
+	if filter.Email != "" {
+		w.add("lower(d.email) = lower(" + w.arg(filter.Email) + ")")
+	}
+	if filter.DeliveryService != "" {
+		w.add("o.delivery_service = " + w.arg(filter.DeliveryService))
+	}
+	if !filter.DateFrom.IsZero() {
+		w.add("o.date_created >= " + w.arg(filter.DateFrom))
+	}
+	if !filter.DateTo.IsZero() {
+		w.add("o.date_created < " + w.arg(filter.DateTo))
+	}
+	if filter.PaymentProvider != "" {
+		w.add("p.provider = " + w.arg(filter.PaymentProvider))
+	}
+	if filter.Bank != "" {
+		w.add("p.bank = " + w.arg(filter.Bank))
+	}
+
+	if filter.HasItemConditions() {
+		itemConds := []string{"i.order_uid = o.order_uid", "i.date_created = o.date_created"}
+		if filter.Brand != "" {
+			itemConds = append(itemConds, "i.brand = "+w.arg(filter.Brand))
+		}
+		if filter.NmID != 0 {
+			itemConds = append(itemConds, "i.nm_id = "+w.arg(filter.NmID))
+		}
+		if filter.ChrtID != 0 {
+			itemConds = append(itemConds, "i.chrt_id = "+w.arg(filter.ChrtID))
+		}
+		w.add("EXISTS (SELECT 1 FROM item i WHERE " + strings.Join(itemConds, " AND ") + ")")
+	}
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveExchangeRates сохраняет курсы, заменяя уже загруженные на те же даты
+func (p *Postgres) SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) (int, error) {
+	dates := make([]time.Time, len(rates))
+	currencies := make([]string, len(rates))
+	values := make([]string, len(rates))
+	for i, rate := range rates {
+		dates[i] = rate.Date
+		currencies[i] = string(rate.Currency)
+		values[i] = rate.Rate
+	}
+
+	query := `INSERT INTO exchange_rates (rate_date, currency, rate)
+		SELECT * FROM unnest($1::date[], $2::varchar[], $3::text[]::numeric[])
+		ON CONFLICT (currency, rate_date) DO UPDATE SET rate = EXCLUDED.rate, updated_at = now()`
+	tag, err := p.pool.Exec(ctx, query, dates, currencies, values)
+	if err != nil {
+		return 0, fmt.Errorf("exchange rate saving error: %w", checkPostgresError(err))
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// GetExchangeRate возвращает курс валюты, действующий на дату date
+func (p *Postgres) GetExchangeRate(ctx context.Context, currency models.Currency, date time.Time) (models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	query := `SELECT rate_date, currency, rate::text FROM exchange_rates
+		WHERE currency = $1 AND rate_date <= $2 ORDER BY rate_date DESC LIMIT 1`
+	err := p.read(ctx, func(q querier) error {
+		err := q.QueryRow(ctx, query, currency, date).Scan(&rate.Date, &rate.Currency, &rate.Rate)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: no %s rate on %s", er.ErrRateNotFound, currency, date.Format(time.DateOnly))
+		}
+		if err != nil {
+			return fmt.Errorf("exchange rate retrieval error: %w", checkPostgresError(err))
+		}
+		return nil
+	})
+	return rate, err
+}
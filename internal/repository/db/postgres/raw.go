@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// insertRawQuery сохраняет исходное сообщение. Повторно доставленное сообщение Kafka
+// (тот же topic/partition/offset) игнорируется; у сообщений HTTP API нет offset, они сохраняются всегда.
+const insertRawQuery = `INSERT INTO order_raw (order_uid, payload, topic, kafka_partition, kafka_offset, ingested_at, major_units)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (topic, kafka_partition, kafka_offset) WHERE topic <> '' AND topic <> 'http' DO NOTHING`
+
+// SaveRawOrder сохраняет исходное сообщение заказа.
+// Повторно доставленное сообщение Kafka (тот же topic/partition/offset) игнорируется;
+// у сообщений HTTP API нет offset, они сохраняются всегда.
+func (p *Postgres) SaveRawOrder(ctx context.Context, raw models.RawOrder) error {
+	_, err := p.pool.Exec(ctx, insertRawQuery, raw.OrderUID, raw.Payload, raw.Topic, raw.Partition, raw.Offset, raw.IngestedAt, raw.MajorUnits)
+	if err != nil {
+		return fmt.Errorf("raw order creation error: %w", checkPostgresError(err))
+	}
+	return nil
+}
+
+// insertRawOrderTx сохраняет исходное сообщение заказа в транзакции
+func insertRawOrderTx(ctx context.Context, tx pgx.Tx, raw models.RawOrder) error {
+	_, err := tx.Exec(ctx, insertRawQuery, raw.OrderUID, raw.Payload, raw.Topic, raw.Partition, raw.Offset, raw.IngestedAt, raw.MajorUnits)
+	if err != nil {
+		return fmt.Errorf("raw order creation error: %w", checkPostgresError(err))
+	}
+	return nil
+}
+
+// GetRawOrders возвращает исходные сообщения заказа, начиная с самого нового
+func (p *Postgres) GetRawOrders(ctx context.Context, orderUID string) ([]models.RawOrder, error) {
+	query := `SELECT order_uid, payload, topic, kafka_partition, kafka_offset, ingested_at, major_units
+		FROM order_raw WHERE order_uid = $1 ORDER BY id DESC`
+	rows, err := p.pool.Query(ctx, query, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("raw order query error: %w", checkPostgresError(err))
+	}
+	defer rows.Close()
+
+	var raws []models.RawOrder
+	for rows.Next() {
+		var raw models.RawOrder
+		var payload []byte
+		if err := rows.Scan(&raw.OrderUID, &payload, &raw.Topic, &raw.Partition, &raw.Offset, &raw.IngestedAt, &raw.MajorUnits); err != nil {
+			return nil, fmt.Errorf("raw order scanning error: %w", checkPostgresError(err))
+		}
+		raw.Payload = payload
+		raws = append(raws, raw)
+	}
+
+	// Проверка на ошибки итерации
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("raw order iteration error: %w", checkPostgresError(err))
+	}
+
+	return raws, nil
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/pkg/er"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// ReplicaConfig - реплики для чтения. Без строк подключения все запросы идут в основную БД.
+type ReplicaConfig struct {
+	ConnectionStrings []string
+	// MaxLag - реплика с большим отставанием не используется
+	MaxLag time.Duration
+	// LagCheckInterval - как часто перепроверять отставание реплики
+	LagCheckInterval time.Duration
+}
+
+// querier - методы чтения, общие для пула и транзакции
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// replicaLagQuery возвращает отставание реплики в секундах и признак потери связи с основной БД.
+// Если все полученные WAL применены, отставание нулевое, даже когда в основную БД давно ничего
+// не писали, поэтому реплика без активного приемника WAL считается отключенной отдельно:
+// иначе она бы выглядела актуальной. На основной БД - 0 и false.
+const replicaLagQuery = `SELECT COALESCE(CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+	END, 0)::float8,
+	pg_is_in_recovery() AND NOT EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming')`
+
+// replica - пул реплики с последним измеренным отставанием
+type replica struct {
+	pool      *pgxpool.Pool
+	healthy   atomic.Bool
+	checkedAt atomic.Int64 // unix nano последней проверки
+	checking  sync.Mutex
+}
+
+// check измеряет отставание реплики, если с прошлой проверки прошло больше interval.
+// Проверку выполняет один запрос, остальные используют предыдущий результат.
+func (r *replica) check(ctx context.Context, cfg ReplicaConfig) {
+	if time.Since(time.Unix(0, r.checkedAt.Load())) < cfg.LagCheckInterval || !r.checking.TryLock() {
+		return
+	}
+	defer r.checking.Unlock()
+
+	var lag float64
+	var disconnected bool
+	err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&lag, &disconnected)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	r.checkedAt.Store(time.Now().UnixNano())
+	if err != nil {
+		zap.S().Warnf("replica %s is unavailable: %v", r.pool.Config().ConnConfig.Host, err)
+		r.healthy.Store(false)
+		return
+	}
+
+	healthy := !disconnected && time.Duration(lag*float64(time.Second)) <= cfg.MaxLag
+	if r.healthy.Swap(healthy) != healthy && !healthy {
+		if disconnected {
+			zap.S().Warnf("replica %s is not streaming WAL from primary", r.pool.Config().ConnConfig.Host)
+		} else {
+			zap.S().Warnf("replica %s lag %.1fs exceeds %s", r.pool.Config().ConnConfig.Host, lag, cfg.MaxLag)
+		}
+	}
+}
+
+// fail помечает реплику недоступной до следующей проверки
+func (r *replica) fail() {
+	r.healthy.Store(false)
+	r.checkedAt.Store(0)
+}
+
+// newReplicas создает пулы реплик с теми же настройками, что у основной БД
+func newReplicas(cfg ReplicaConfig, poolCfg PoolConfig) ([]*replica, error) {
+	replicas := make([]*replica, 0, len(cfg.ConnectionStrings))
+	for _, connString := range cfg.ConnectionStrings {
+		pool, err := newPool(connString, poolCfg)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+		replicas = append(replicas, &replica{pool: pool})
+	}
+	return replicas, nil
+}
+
+// closeReplicas закрывает пулы реплик
+func closeReplicas(replicas []*replica) {
+	for _, r := range replicas {
+		r.pool.Close()
+	}
+}
+
+type primaryKey struct{}
+
+// WithPrimary направляет чтения с этим контекстом в основную БД.
+// Используется для чтения сразу после записи, которую реплика могла еще не получить.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// pickReplica выбирает реплику с допустимым отставанием по кругу, nil - читать из основной БД
+func (p *Postgres) pickReplica(ctx context.Context) *replica {
+	if len(p.replicas) == 0 || ctx.Value(primaryKey{}) != nil {
+		return nil
+	}
+	start := p.next.Add(1)
+	for i := range p.replicas {
+		r := p.replicas[(int(start)+i)%len(p.replicas)]
+		r.check(ctx, p.replicaCfg)
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// read выполняет чтение на реплике, а при ошибке повторяет его в основной БД.
+// Заказ, не найденный на реплике, тоже ищется в основной БД: реплика могла его еще не получить.
+func (p *Postgres) read(ctx context.Context, fn func(q querier) error) error {
+	if r := p.pickReplica(ctx); r != nil {
+		err := fn(r.pool)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if errors.Is(err, er.ErrDatabaseError) {
+			zap.S().Warnf("replica read failed, falling back to primary: %v", err)
+			r.fail()
+		}
+	}
+	return fn(p.pool)
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AppendStatusChange переводит заказ и его товары в новый статус и дописывает историю.
+// Переход выполняется, только если текущий статус заказа равен change.From,
+// а версия - expectedVersion (0 - без проверки версии).
+func (p *Postgres) AppendStatusChange(ctx context.Context, change models.StatusChange, expectedVersion int64) error {
+	return p.withTx(ctx, func(tx pgx.Tx) error {
+		return p.appendStatusChangeTx(ctx, tx, change, expectedVersion, true)
+	})
+}
+
+// appendStatusChangeTx выполняет AppendStatusChange в транзакции tx. Если bump false, версия
+// не увеличивается: ее уже увеличило изменение данных заказа в той же транзакции.
+func (p *Postgres) appendStatusChangeTx(ctx context.Context, tx pgx.Tx, change models.StatusChange, expectedVersion int64, bump bool) error {
+	query := `UPDATE orders SET status = $3, version = version + CASE WHEN $5 THEN 1 ELSE 0 END
+		WHERE order_uid = $1 AND date_created = ` + partitionKey("$1") + ` AND status = $2 AND deleted_at IS NULL
+			AND ($4 = 0 OR version = $4)
+		RETURNING version`
+	var version int64
+	err := tx.QueryRow(ctx, query, change.OrderUID, change.From, change.To, expectedVersion, bump).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p.statusConflict(ctx, tx, change.OrderUID, expectedVersion)
+	}
+	if err != nil {
+		return fmt.Errorf("order status update error: %w", checkPostgresError(err))
+	}
+
+	query = `UPDATE item SET status = $2 WHERE order_uid = $1 AND date_created = ` + partitionKey("$1")
+	if _, err := tx.Exec(ctx, query, change.OrderUID, change.To); err != nil {
+		return fmt.Errorf("item status update error: %w", checkPostgresError(err))
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO order_status_history (order_uid, from_status, to_status, comment, changed_at) VALUES ($1, $2, $3, $4, $5)`,
+		change.OrderUID, change.From, change.To, change.Comment, change.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("status history creation error: %w", checkPostgresError(err))
+	}
+
+	return auditTx(ctx, tx, change.OrderUID, models.AuditStatus, version,
+		map[string]any{"status": change.From.String()},
+		map[string]any{"status": change.To.String(), "comment": change.Comment})
+}
+
+// statusConflict объясняет, почему переход статуса не затронул ни одной строки
+func (p *Postgres) statusConflict(ctx context.Context, tx pgx.Tx, orderUID string, expectedVersion int64) error {
+	var current models.Status
+	var version int64
+	query := `SELECT status, version FROM orders WHERE order_uid = $1 AND date_created = ` + partitionKey("$1") + ` AND deleted_at IS NULL`
+	err := tx.QueryRow(ctx, query, orderUID).Scan(&current, &version)
+	if err != nil {
+		return fmt.Errorf("order retrieval error: %w", checkPostgresError(err))
+	}
+	if expectedVersion != 0 && version != expectedVersion {
+		return fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, version)
+	}
+	return fmt.Errorf("%w: order status is already %s", er.ErrInvalidStatusTransition, current)
+}
+
+// GetStatusHistory возвращает историю статусов заказа в хронологическом порядке
+func (p *Postgres) GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error) {
+	query := `SELECT order_uid, from_status, to_status, comment, changed_at
+		FROM order_status_history WHERE order_uid = $1 ORDER BY id`
+	rows, err := p.pool.Query(ctx, query, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("status history query error: %w", checkPostgresError(err))
+	}
+	defer rows.Close()
+
+	var history []models.StatusChange
+	for rows.Next() {
+		var change models.StatusChange
+		if err := rows.Scan(&change.OrderUID, &change.From, &change.To, &change.Comment, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("status history scanning error: %w", checkPostgresError(err))
+		}
+		history = append(history, change)
+	}
+
+	// Проверка на ошибки итерации
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("status history iteration error: %w", checkPostgresError(err))
+	}
+
+	return history, nil
+}
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lockedOrder - строка заказа, заблокированная для изменения
+type lockedOrder struct {
+	deliveryID   int
+	paymentID    int
+	dateCreated  time.Time
+	version      int64
+	eventVersion int64
+	deleted      bool
+}
+
+// lockOrderTx блокирует строку заказа до конца транзакции. Если заказа нет - ErrOrderNotFound.
+func lockOrderTx(ctx context.Context, tx pgx.Tx, orderUID string) (lockedOrder, error) {
+	var locked lockedOrder
+	query := `SELECT delivery_id, payment_id, date_created, version, event_version, deleted_at IS NOT NULL FROM orders
+		WHERE order_uid = $1 AND date_created = ` + partitionKey("$1") + ` FOR UPDATE`
+	err := tx.QueryRow(ctx, query, orderUID).
+		Scan(&locked.deliveryID, &locked.paymentID, &locked.dateCreated, &locked.version, &locked.eventVersion, &locked.deleted)
+	if err != nil {
+		return locked, fmt.Errorf("order retrieval error: %w", checkPostgresError(err))
+	}
+	return locked, nil
+}
+
+// UpdateOrder перезаписывает данные заказа, если его текущая версия равна expectedVersion
+func (p *Postgres) UpdateOrder(ctx context.Context, order models.Order, expectedVersion int64) error {
+	return p.PatchOrder(ctx, order, nil, expectedVersion)
+}
+
+// PatchOrder перезаписывает данные заказа и, если change не nil, меняет его статус в одной транзакции.
+// Заказ изменяется, только если его текущая версия равна expectedVersion.
+func (p *Postgres) PatchOrder(ctx context.Context, order models.Order, change *models.StatusChange, expectedVersion int64) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	return p.withTx(ctx, func(tx pgx.Tx) error {
+		locked, err := lockOrderTx(ctx, tx, order.OrderUID)
+		if err != nil {
+			return err
+		}
+		if locked.deleted {
+			return fmt.Errorf("order retrieval error: %w", er.ErrOrderNotFound)
+		}
+		if locked.version != expectedVersion {
+			return fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, locked.version)
+		}
+		if err := p.updateOrderTx(ctx, tx, order, locked); err != nil {
+			return err
+		}
+		if change == nil {
+			return nil
+		}
+		// Строка заблокирована, версию после обновления проверять не нужно.
+		// Патч увеличивает версию один раз.
+		return p.appendStatusChangeTx(ctx, tx, *change, 0, false)
+	})
+}
+
+// ApplyOrderEvent создает заказ из события или обновляет существующий, если версия события
+// новее уже примененной. Возвращает false, если событие устарело и проигнорировано.
+func (p *Postgres) ApplyOrderEvent(ctx context.Context, order models.Order) (bool, error) {
+	if err := order.Validate(); err != nil {
+		return false, err
+	}
+
+	var applied bool
+	err := p.withTx(ctx, func(tx pgx.Tx) error {
+		locked, err := lockOrderTx(ctx, tx, order.OrderUID)
+		if errors.Is(err, er.ErrOrderNotFound) {
+			applied = true
+			return p.createOrderTx(ctx, tx, order)
+		}
+		if err != nil {
+			return err
+		}
+		if locked.deleted || order.EventVersion <= locked.eventVersion {
+			return nil
+		}
+		applied = true
+		return p.updateOrderTx(ctx, tx, order, locked)
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, nil
+}
+
+// versionConflict объясняет, почему условное изменение активного заказа не затронуло ни одной строки
+func versionConflict(ctx context.Context, q querier, orderUID string, expectedVersion int64) error {
+	var version int64
+	query := `SELECT version FROM orders WHERE order_uid = $1 AND date_created = ` + partitionKey("$1") + ` AND deleted_at IS NULL`
+	if err := q.QueryRow(ctx, query, orderUID).Scan(&version); err != nil {
+		return fmt.Errorf("order retrieval error: %w", checkPostgresError(err))
+	}
+	if expectedVersion != 0 && version != expectedVersion {
+		return fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, version)
+	}
+	return nil
+}
+
+// GetOrderChanges возвращает версии активных заказов, измененных позже since, и заказы,
+// удаленные позже since: мягко удаленные находятся по updated_at, безвозвратно удаленные
+// и перенесенные в архив - по записям purge журнала аудита. При нулевом since
+// возвращаются версии всех активных заказов.
+func (p *Postgres) GetOrderChanges(ctx context.Context, since time.Time) (models.OrderChanges, error) {
+	var changes models.OrderChanges
+	err := p.read(ctx, func(q querier) error {
+		changes = models.OrderChanges{Versions: make(map[string]int64)}
+		// Метка берется до чтения: изменения, зафиксированные во время чтения, попадут в следующий запрос
+		if err := q.QueryRow(ctx, `SELECT localtimestamp`).Scan(&changes.Watermark); err != nil {
+			return fmt.Errorf("order changes query error: %w", checkPostgresError(err))
+		}
+
+		query := `SELECT o.order_uid, o.version, o.deleted_at IS NOT NULL FROM orders o WHERE ` + activeOrder
+		args := []any{}
+		if !since.IsZero() {
+			query = `SELECT o.order_uid, o.version, o.deleted_at IS NOT NULL FROM orders o WHERE o.updated_at > $1`
+			args = append(args, since)
+		}
+		rows, err := q.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("order changes query error: %w", checkPostgresError(err))
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var orderUID string
+			var version int64
+			var deleted bool
+			if err := rows.Scan(&orderUID, &version, &deleted); err != nil {
+				return fmt.Errorf("order changes scanning error: %w", checkPostgresError(err))
+			}
+			if deleted {
+				changes.Removed = append(changes.Removed, orderUID)
+			} else {
+				changes.Versions[orderUID] = version
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("order changes iteration error: %w", checkPostgresError(err))
+		}
+		if since.IsZero() {
+			return nil
+		}
+
+		purged, err := q.Query(ctx, `SELECT DISTINCT order_uid FROM order_audit WHERE action = $1 AND changed_at > $2`,
+			models.AuditPurge, since)
+		if err != nil {
+			return fmt.Errorf("purged orders query error: %w", checkPostgresError(err))
+		}
+		uids, err := pgx.CollectRows(purged, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("purged orders scanning error: %w", checkPostgresError(err))
+		}
+		changes.Removed = append(changes.Removed, uids...)
+		return nil
+	})
+	return changes, err
+}
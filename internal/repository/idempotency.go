@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+)
+
+// ReserveIdempotencyKey занимает ключ идемпотентности. Если ключ уже занят и не старше ttl,
+// возвращает сохраненную запись и false. Ключ без ответа старше lease (обработка прервалась
+// падением процесса) занимается заново.
+func (r *Repository) ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl, lease time.Duration) (models.IdempotencyKey, bool, error) {
+	if key.Key == "" || len(key.Key) > 255 {
+		return models.IdempotencyKey{}, false, fmt.Errorf("%w: idempotency key must be 1 to 255 characters", er.ErrInvalidData)
+	}
+	return r.db.ReserveIdempotencyKey(ctx, key, ttl, lease)
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, client, key string, statusCode int, response []byte) error {
+	return r.db.CompleteIdempotencyKey(ctx, client, key, statusCode, response)
+}
+
+// ReleaseIdempotencyKey освобождает ключ, чтобы запрос можно было повторить
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, client, key string) error {
+	return r.db.ReleaseIdempotencyKey(ctx, client, key)
+}
+
+// PurgeIdempotencyKeys удаляет ключи старше ttl
+func (r *Repository) PurgeIdempotencyKeys(ctx context.Context, ttl time.Duration) (int, error) {
+	return r.db.PurgeIdempotencyKeys(ctx, ttl)
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PartitionConfig - обслуживание месячных секций orders и item
+type PartitionConfig struct {
+	// MonthsAhead - на сколько месяцев вперед создаются секции
+	MonthsAhead int `env:"PARTITION_MONTHS_AHEAD" envDefault:"3"`
+	// DetachAfterMonths - секции старше стольких месяцев отсоединяются (0 - не отсоединять)
+	DetachAfterMonths int           `env:"PARTITION_DETACH_AFTER_MONTHS"`
+	Interval          time.Duration `env:"PARTITION_MAINTENANCE_INTERVAL" envDefault:"24h"`
+}
+
+// partitionManager - драйвер, хранящий заказы в секционированных таблицах
+type partitionManager interface {
+	CreatePartitions(ctx context.Context, from, to time.Time) ([]string, error)
+	DetachPartitions(ctx context.Context, before time.Time) ([]string, error)
+}
+
+// MaintainPartitions создает секции на MonthsAhead месяцев вперед и отсоединяет
+// секции старше DetachAfterMonths месяцев
+func (r *Repository) MaintainPartitions(ctx context.Context) error {
+	if r.partitions == nil {
+		return nil
+	}
+	cfg := r.cfg.Partitions
+	now := time.Now().UTC()
+
+	created, err := r.partitions.CreatePartitions(ctx, now, now.AddDate(0, cfg.MonthsAhead, 0))
+	for _, name := range created {
+		zap.S().Infof("partition %s created", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cfg.DetachAfterMonths <= 0 {
+		return nil
+	}
+	before := time.Date(now.Year(), now.Month()-time.Month(cfg.DetachAfterMonths), 1, 0, 0, 0, 0, time.UTC)
+	detached, err := r.partitions.DetachPartitions(ctx, before)
+	for _, name := range detached {
+		zap.S().Infof("partition %s detached", name)
+	}
+	return err
+}
+
+// RunPartitionMaintenance периодически обслуживает секции до отмены контекста
+func (r *Repository) RunPartitionMaintenance(ctx context.Context) {
+	if r.partitions == nil || r.cfg.Partitions.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.Partitions.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.MaintainPartitions(ctx); err != nil && ctx.Err() == nil {
+			zap.S().Errorf("failed to maintain partitions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
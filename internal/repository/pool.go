@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/repository/db/postgres"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxConnectBackoff ограничивает паузу между попытками подключения
+const maxConnectBackoff = 30 * time.Second
+
+// PoolConfig - настройки пула соединений Postgres. Нулевые значения - настройки pgxpool по умолчанию.
+type PoolConfig struct {
+	MaxConns          int32         `env:"DB_MAX_CONNS"`
+	MinConns          int32         `env:"DB_MIN_CONNS"`
+	MaxConnLifetime   time.Duration `env:"DB_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime   time.Duration `env:"DB_MAX_CONN_IDLE_TIME"`
+	HealthCheckPeriod time.Duration `env:"DB_HEALTH_CHECK_PERIOD"`
+	ConnectTimeout    time.Duration `env:"DB_CONNECT_TIMEOUT" envDefault:"5s"`
+	StatementTimeout  time.Duration `env:"DB_STATEMENT_TIMEOUT"`
+	// ConnectRetries - число повторных попыток подключения при старте, пауза удваивается от ConnectBackoff
+	ConnectRetries int           `env:"DB_CONNECT_RETRIES" envDefault:"5"`
+	ConnectBackoff time.Duration `env:"DB_CONNECT_BACKOFF" envDefault:"1s"`
+}
+
+func (c PoolConfig) postgres() postgres.PoolConfig {
+	return postgres.PoolConfig{
+		MaxConns:          c.MaxConns,
+		MinConns:          c.MinConns,
+		MaxConnLifetime:   c.MaxConnLifetime,
+		MaxConnIdleTime:   c.MaxConnIdleTime,
+		HealthCheckPeriod: c.HealthCheckPeriod,
+		ConnectTimeout:    c.ConnectTimeout,
+		StatementTimeout:  c.StatementTimeout,
+	}
+}
+
+// pingWithRetry проверяет соединение с БД, повторяя попытки с экспоненциальной паузой
+func pingWithRetry(ctx context.Context, db *postgres.Postgres, cfg PoolConfig) error {
+	backoff := cfg.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		err := db.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			return fmt.Errorf("failed to connect to database after %d attempts: %w", attempt+1, err)
+		}
+
+		zap.S().Warnf("database is unavailable, retrying in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// Health - состояние хранилища для проверки здоровья и метрик
+type Health struct {
+	Driver  string               `json:"driver"`
+	Healthy bool                 `json:"healthy"`
+	Error   string               `json:"error,omitempty"`
+	Pools   []postgres.PoolStats `json:"pools,omitempty"`
+}
+
+// healthChecker - драйвер, умеющий проверять соединение
+type healthChecker interface {
+	Health(ctx context.Context) ([]postgres.PoolStats, error)
+}
+
+// Health проверяет доступность основной БД и возвращает статистику пулов соединений
+func (r *Repository) Health(ctx context.Context) Health {
+	health := Health{Driver: r.cfg.Driver, Healthy: true}
+	if health.Driver == "" {
+		health.Driver = DriverPostgres
+	}
+
+	checker, ok := r.db.(healthChecker)
+	if !ok {
+		return health
+	}
+	pools, err := checker.Health(ctx)
+	health.Pools = pools
+	if err != nil {
+		health.Healthy = false
+		health.Error = err.Error()
+	}
+	return health
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+)
+
+// SaveExchangeRates проверяет и сохраняет курсы валют, заменяя уже загруженные на те же даты.
+// Если одна дата и валюта встречаются несколько раз, сохраняется последний курс.
+func (r *Repository) SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) (int, error) {
+	if len(rates) == 0 {
+		return 0, fmt.Errorf("%w: no exchange rates to save", er.ErrInvalidData)
+	}
+
+	type rateKey struct {
+		currency models.Currency
+		date     time.Time
+	}
+	index := make(map[rateKey]int, len(rates))
+	unique := make([]models.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		if err := rate.Validate(); err != nil {
+			return 0, err
+		}
+		rate.Date = time.Date(rate.Date.Year(), rate.Date.Month(), rate.Date.Day(), 0, 0, 0, 0, time.UTC)
+		key := rateKey{rate.Currency, rate.Date}
+		if i, ok := index[key]; ok {
+			unique[i] = rate
+			continue
+		}
+		index[key] = len(unique)
+		unique = append(unique, rate)
+	}
+	return r.db.SaveExchangeRates(ctx, unique)
+}
+
+// GetExchangeRate возвращает курс валюты к RateBaseCurrency, действующий на дату date.
+// Курс базовой валюты всегда 1.
+func (r *Repository) GetExchangeRate(ctx context.Context, currency models.Currency, date time.Time) (models.ExchangeRate, error) {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if currency == models.RateBaseCurrency {
+		return models.ExchangeRate{Date: date, Currency: currency, Rate: "1"}, nil
+	}
+	if !currency.Valid() {
+		return models.ExchangeRate{}, fmt.Errorf("%w: unknown currency %q, expected ISO 4217 code", er.ErrInvalidData, currency)
+	}
+	return r.db.GetExchangeRate(ctx, currency, date)
+}
@@ -2,35 +2,425 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"l0/internal/models"
+	"l0/internal/repository/db/memory"
 	"l0/internal/repository/db/postgres"
+	"l0/pkg/er"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые драйверы хранилища
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
 )
 
 type Config struct {
-	ConnectionString string `env:"DB_CONNECTION_STRING,required"`
+	Driver           string `env:"DB_DRIVER" envDefault:"postgres"`
+	ConnectionString string `env:"DB_CONNECTION_STRING"`
+	// Реплики для чтения заказов и поиска (необязательно)
+	ReplicaConnectionStrings []string      `env:"DB_REPLICA_CONNECTION_STRINGS" envSeparator:","`
+	ReplicaMaxLag            time.Duration `env:"DB_REPLICA_MAX_LAG" envDefault:"5s"`
+	ReplicaLagCheckInterval  time.Duration `env:"DB_REPLICA_LAG_CHECK_INTERVAL" envDefault:"1s"`
+	Pool                     PoolConfig
+	Partitions               PartitionConfig
+	Analytics                AnalyticsConfig
+}
+
+// WithPrimary направляет чтения с этим контекстом в основную БД, минуя реплики.
+// Нужен для чтения сразу после записи.
+func WithPrimary(ctx context.Context) context.Context {
+	return postgres.WithPrimary(ctx)
 }
 
+// OrderRepository - хранилище заказов, от которого зависит сервисный слой
+type OrderRepository interface {
+	GetOrder(ctx context.Context, orderUID string) (models.Order, error)
+	GetOrdersByUIDs(ctx context.Context, orderUIDs []string) ([]models.Order, error)
+	CreateOrder(ctx context.Context, order models.Order) error
+	CreateOrderWithRaw(ctx context.Context, order models.Order, raw models.RawOrder) error
+	GetAllOrders(ctx context.Context) ([]models.Order, error)
+	GetOrderChanges(ctx context.Context, since time.Time) (models.OrderChanges, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter, cursor string, limit int) (models.OrderPage, error)
+	SearchOrdersFullText(ctx context.Context, query string, limit int) ([]models.SearchHit, error)
+	ReplaceOrder(ctx context.Context, order models.Order) error
+	UpdateOrder(ctx context.Context, order models.Order, expectedVersion int64) error
+	PatchOrder(ctx context.Context, order models.Order, change *models.StatusChange, expectedVersion int64) error
+	ApplyOrderEvent(ctx context.Context, order models.Order) (bool, error)
+	SaveRawOrder(ctx context.Context, raw models.RawOrder) error
+	GetRawOrders(ctx context.Context, orderUID string) ([]models.RawOrder, error)
+	AppendStatusChange(ctx context.Context, change models.StatusChange, expectedVersion int64) error
+	GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error)
+	GetOrderAudit(ctx context.Context, orderUID string) ([]models.AuditEntry, error)
+	SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) (int, error)
+	GetExchangeRate(ctx context.Context, currency models.Currency, date time.Time) (models.ExchangeRate, error)
+	SalesStats(ctx context.Context, query models.SalesQuery) ([]models.SalesRow, error)
+	GetCustomerSummary(ctx context.Context, customerID string, topBrands int) (models.CustomerSummary, error)
+	ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl, lease time.Duration) (models.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, client, key string, statusCode int, response []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, client, key string) error
+	PurgeIdempotencyKeys(ctx context.Context, ttl time.Duration) (int, error)
+	Health(ctx context.Context) Health
+	SoftDeleteOrder(ctx context.Context, orderUID string, expectedVersion int64) error
+	RestoreOrder(ctx context.Context, orderUID string) error
+	ListExpiredOrders(ctx context.Context, before time.Time, deleted bool, limit int) ([]models.Order, error)
+	PurgeOrders(ctx context.Context, orderUIDs []string) (int, error)
+	GetStatusHistories(ctx context.Context, orderUIDs []string) (map[string][]models.StatusChange, error)
+	GetRawOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.RawOrder, error)
+	ImportOrder(ctx context.Context, order models.Order, history []models.StatusChange, raws []models.RawOrder) error
+	Close()
+}
+
+// storage - операции, которые реализует каждый драйвер БД
+type storage interface {
+	GetOrder(ctx context.Context, orderUID string) (models.Order, error)
+	GetOrdersByUIDs(ctx context.Context, orderUIDs []string) ([]models.Order, error)
+	CreateOrder(ctx context.Context, order models.Order) error
+	CreateOrderWithRaw(ctx context.Context, order models.Order, raw models.RawOrder) error
+	GetOrders(ctx context.Context) ([]models.Order, error)
+	GetOrderChanges(ctx context.Context, since time.Time) (models.OrderChanges, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter, after *models.Cursor, limit int) ([]models.Order, error)
+	SearchOrdersFullText(ctx context.Context, query string, limit int) ([]models.SearchHit, error)
+	ReplaceOrder(ctx context.Context, order models.Order) error
+	UpdateOrder(ctx context.Context, order models.Order, expectedVersion int64) error
+	PatchOrder(ctx context.Context, order models.Order, change *models.StatusChange, expectedVersion int64) error
+	ApplyOrderEvent(ctx context.Context, order models.Order) (bool, error)
+	SaveRawOrder(ctx context.Context, raw models.RawOrder) error
+	GetRawOrders(ctx context.Context, orderUID string) ([]models.RawOrder, error)
+	AppendStatusChange(ctx context.Context, change models.StatusChange, expectedVersion int64) error
+	GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error)
+	GetOrderAudit(ctx context.Context, orderUID string) ([]models.AuditEntry, error)
+	SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) (int, error)
+	GetExchangeRate(ctx context.Context, currency models.Currency, date time.Time) (models.ExchangeRate, error)
+	SalesStats(ctx context.Context, query models.SalesQuery) ([]models.SalesRow, error)
+	GetCustomerSummary(ctx context.Context, customerID string, topBrands int) (models.CustomerSummary, error)
+	ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl, lease time.Duration) (models.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, client, key string, statusCode int, response []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, client, key string) error
+	PurgeIdempotencyKeys(ctx context.Context, ttl time.Duration) (int, error)
+	SoftDeleteOrder(ctx context.Context, orderUID string, expectedVersion int64) error
+	RestoreOrder(ctx context.Context, orderUID string) error
+	ListExpiredOrders(ctx context.Context, before time.Time, deleted bool, limit int) ([]models.Order, error)
+	PurgeOrders(ctx context.Context, orderUIDs []string) (int, error)
+	GetStatusHistories(ctx context.Context, orderUIDs []string) (map[string][]models.StatusChange, error)
+	GetRawOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.RawOrder, error)
+	ImportOrder(ctx context.Context, order models.Order, history []models.StatusChange, raws []models.RawOrder) error
+	Close()
+}
+
+var (
+	_ OrderRepository    = (*Repository)(nil)
+	_ storage            = (*postgres.Postgres)(nil)
+	_ storage            = (*memory.Memory)(nil)
+	_ partitionManager   = (*postgres.Postgres)(nil)
+	_ analyticsRefresher = (*postgres.Postgres)(nil)
+)
+
 type Repository struct {
-	db *postgres.Postgres
+	cfg        Config
+	db         storage
+	partitions partitionManager   // nil, если драйвер не секционирует таблицы
+	analytics  analyticsRefresher // nil, если драйвер считает агрегаты при чтении
 }
 
 func NewRepository(cfg Config) (*Repository, error) {
-	db, err := postgres.NewPostgres(cfg.ConnectionString)
+	switch cfg.Driver {
+	case DriverPostgres, "":
+		if cfg.ConnectionString == "" {
+			return nil, fmt.Errorf("DB_CONNECTION_STRING is required for %s driver", DriverPostgres)
+		}
+		db, err := postgres.NewPostgres(cfg.ConnectionString, cfg.Pool.postgres(), postgres.ReplicaConfig{
+			ConnectionStrings: cfg.ReplicaConnectionStrings,
+			MaxLag:            cfg.ReplicaMaxLag,
+			LagCheckInterval:  cfg.ReplicaLagCheckInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := pingWithRetry(context.Background(), db, cfg.Pool); err != nil {
+			db.Close()
+			return nil, err
+		}
+		// Не стартуем на схеме старее той, что ожидает бинарник
+		migrator, err := db.Migrator()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		if err := migrator.CheckVersion(context.Background()); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return &Repository{cfg: cfg, db: db, partitions: db, analytics: db}, nil
+	case DriverMemory:
+		return &Repository{cfg: cfg, db: memory.NewMemory()}, nil
+	default:
+		return nil, fmt.Errorf("unknown DB driver %q", cfg.Driver)
+	}
+}
+
+// NewMigrator создает мигратор схемы Postgres без проверки версии схемы.
+// Мигратор владеет своим пулом соединений, после работы его нужно закрыть.
+func NewMigrator(cfg Config) (*postgres.Migrator, error) {
+	if cfg.Driver != DriverPostgres && cfg.Driver != "" {
+		return nil, fmt.Errorf("migrations are supported only for %s driver", DriverPostgres)
+	}
+	if cfg.ConnectionString == "" {
+		return nil, fmt.Errorf("DB_CONNECTION_STRING is required for %s driver", DriverPostgres)
+	}
+	// Миграции могут выполняться дольше statement_timeout сервера
+	poolCfg := cfg.Pool
+	poolCfg.StatementTimeout = 0
+	db, err := postgres.NewPostgres(cfg.ConnectionString, poolCfg.postgres(), postgres.ReplicaConfig{})
+	if err != nil {
+		return nil, err
+	}
+	if err := pingWithRetry(context.Background(), db, cfg.Pool); err != nil {
+		db.Close()
+		return nil, err
+	}
+	migrator, err := db.Migrator()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
-	repo := &Repository{db: db}
-	return repo, nil
+	return migrator, nil
+}
+
+// Close закрывает соединения с хранилищем
+func (r *Repository) Close() {
+	r.db.Close()
 }
 
 func (r *Repository) GetOrder(ctx context.Context, orderUID string) (models.Order, error) {
 	return r.db.GetOrder(ctx, orderUID)
 }
 
+// GetOrdersByUIDs возвращает найденные заказы из списка, отсутствующие пропускаются
+func (r *Repository) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) ([]models.Order, error) {
+	if len(orderUIDs) == 0 {
+		return nil, nil
+	}
+	return r.db.GetOrdersByUIDs(ctx, orderUIDs)
+}
+
 func (r *Repository) CreateOrder(ctx context.Context, order models.Order) error {
 	return r.db.CreateOrder(ctx, order)
 }
 
+// CreateOrderWithRaw создает заказ и сохраняет его исходное сообщение одной транзакцией:
+// если заказ не создан, сообщение тоже не сохраняется
+func (r *Repository) CreateOrderWithRaw(ctx context.Context, order models.Order, raw models.RawOrder) error {
+	if raw.OrderUID != order.OrderUID {
+		return fmt.Errorf("%w: raw payload belongs to order %q", er.ErrInvalidData, raw.OrderUID)
+	}
+	if !json.Valid(raw.Payload) {
+		return fmt.Errorf("%w: raw payload is not valid JSON", er.ErrInvalidData)
+	}
+	return r.db.CreateOrderWithRaw(ctx, order, raw)
+}
+
 func (r *Repository) GetAllOrders(ctx context.Context) ([]models.Order, error) {
 	return r.db.GetOrders(ctx)
 }
+
+// GetOrderChanges возвращает версии активных заказов, измененных позже since, и заказы,
+// удаленные позже since. При нулевом since возвращаются версии всех активных заказов.
+func (r *Repository) GetOrderChanges(ctx context.Context, since time.Time) (models.OrderChanges, error) {
+	return r.db.GetOrderChanges(ctx, since)
+}
+
+// ListOrders возвращает страницу заказов, упорядоченных по (date_created, order_uid).
+// cursor - непрозрачный курсор из предыдущей страницы, пустой для первой.
+func (r *Repository) ListOrders(ctx context.Context, filter models.OrderFilter, cursor string, limit int) (models.OrderPage, error) {
+	if limit <= 0 {
+		return models.OrderPage{}, fmt.Errorf("%w: limit must be greater than zero", er.ErrInvalidData)
+	}
+
+	var after *models.Cursor
+	if cursor != "" {
+		c, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return models.OrderPage{}, err
+		}
+		after = &c
+	}
+
+	// Запрашиваем на один заказ больше, чтобы понять, есть ли следующая страница
+	orders, err := r.db.ListOrders(ctx, filter, after, limit+1)
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+
+	page := models.OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		last := page.Orders[limit-1]
+		page.NextCursor = models.Cursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}.Encode()
+	}
+	return page, nil
+}
+
+// GetCustomerSummary возвращает сводку по заказам покупателя и до topBrands самых частых брендов
+func (r *Repository) GetCustomerSummary(ctx context.Context, customerID string, topBrands int) (models.CustomerSummary, error) {
+	if customerID == "" {
+		return models.CustomerSummary{}, fmt.Errorf("%w: customer_id is required", er.ErrInvalidData)
+	}
+	if topBrands <= 0 {
+		return models.CustomerSummary{}, fmt.Errorf("%w: brand limit must be greater than zero", er.ErrInvalidData)
+	}
+	return r.db.GetCustomerSummary(ctx, customerID, topBrands)
+}
+
+// SearchOrdersFullText ищет заказы по названиям и брендам товаров и адресу доставки
+func (r *Repository) SearchOrdersFullText(ctx context.Context, query string, limit int) ([]models.SearchHit, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("%w: search query cannot be empty", er.ErrInvalidData)
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be greater than zero", er.ErrInvalidData)
+	}
+	return r.db.SearchOrdersFullText(ctx, query, limit)
+}
+
+// ReplaceOrder создает заказ или полностью заменяет данные существующего
+func (r *Repository) ReplaceOrder(ctx context.Context, order models.Order) error {
+	return r.db.ReplaceOrder(ctx, order)
+}
+
+// UpdateOrder перезаписывает данные заказа, если его текущая версия равна expectedVersion,
+// иначе возвращает ErrVersionConflict
+func (r *Repository) UpdateOrder(ctx context.Context, order models.Order, expectedVersion int64) error {
+	if expectedVersion <= 0 {
+		return fmt.Errorf("%w: expected version must be greater than zero", er.ErrInvalidData)
+	}
+	return r.db.UpdateOrder(ctx, order, expectedVersion)
+}
+
+// PatchOrder перезаписывает данные заказа и, если change не nil, меняет его статус в одной транзакции.
+// Заказ изменяется, только если его текущая версия равна expectedVersion.
+func (r *Repository) PatchOrder(ctx context.Context, order models.Order, change *models.StatusChange, expectedVersion int64) error {
+	if expectedVersion <= 0 {
+		return fmt.Errorf("%w: expected version must be greater than zero", er.ErrInvalidData)
+	}
+	if change != nil && change.OrderUID != order.OrderUID {
+		return fmt.Errorf("%w: status change belongs to another order", er.ErrInvalidData)
+	}
+	return r.db.PatchOrder(ctx, order, change, expectedVersion)
+}
+
+// ApplyOrderEvent создает или обновляет заказ из события брокера. События с event_version
+// не новее уже примененной игнорируются, тогда возвращается false.
+func (r *Repository) ApplyOrderEvent(ctx context.Context, order models.Order) (bool, error) {
+	if order.EventVersion < 0 {
+		return false, fmt.Errorf("%w: event_version cannot be negative", er.ErrInvalidData)
+	}
+	return r.db.ApplyOrderEvent(ctx, order)
+}
+
+// SaveRawOrder сохраняет исходное сообщение заказа
+func (r *Repository) SaveRawOrder(ctx context.Context, raw models.RawOrder) error {
+	if raw.OrderUID == "" {
+		return fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+	if !json.Valid(raw.Payload) {
+		return fmt.Errorf("%w: raw payload is not valid JSON", er.ErrInvalidData)
+	}
+	return r.db.SaveRawOrder(ctx, raw)
+}
+
+// GetRawOrders возвращает исходные сообщения заказа, начиная с самого нового
+func (r *Repository) GetRawOrders(ctx context.Context, orderUID string) ([]models.RawOrder, error) {
+	if orderUID == "" {
+		return nil, fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+	return r.db.GetRawOrders(ctx, orderUID)
+}
+
+// AppendStatusChange переводит заказ в новый статус и дописывает историю статусов.
+// Если expectedVersion не 0, переход выполняется только при совпадении версии заказа.
+func (r *Repository) AppendStatusChange(ctx context.Context, change models.StatusChange, expectedVersion int64) error {
+	if change.OrderUID == "" {
+		return fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+	if !change.To.Valid() {
+		return fmt.Errorf("%w: unknown status %d", er.ErrInvalidData, change.To)
+	}
+	return r.db.AppendStatusChange(ctx, change, expectedVersion)
+}
+
+// GetStatusHistory возвращает историю статусов заказа в хронологическом порядке
+func (r *Repository) GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error) {
+	if orderUID == "" {
+		return nil, fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+	return r.db.GetStatusHistory(ctx, orderUID)
+}
+
+// GetOrderAudit возвращает журнал аудита заказа в хронологическом порядке.
+// Источник каждого изменения берется из контекста записи, см. models.WithAuditSource.
+func (r *Repository) GetOrderAudit(ctx context.Context, orderUID string) ([]models.AuditEntry, error) {
+	if orderUID == "" {
+		return nil, fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+	return r.db.GetOrderAudit(ctx, orderUID)
+}
+
+// SoftDeleteOrder помечает заказ удаленным, после чего он не возвращается при чтении.
+// Если expectedVersion не 0, заказ удаляется только при совпадении версии.
+func (r *Repository) SoftDeleteOrder(ctx context.Context, orderUID string, expectedVersion int64) error {
+	if orderUID == "" {
+		return fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+	return r.db.SoftDeleteOrder(ctx, orderUID, expectedVersion)
+}
+
+// RestoreOrder снимает пометку удаления с заказа
+func (r *Repository) RestoreOrder(ctx context.Context, orderUID string) error {
+	if orderUID == "" {
+		return fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+	}
+	return r.db.RestoreOrder(ctx, orderUID)
+}
+
+// ListExpiredOrders возвращает до limit самых старых заказов, созданных раньше before:
+// мягко удаленные, если deleted, иначе активные
+func (r *Repository) ListExpiredOrders(ctx context.Context, before time.Time, deleted bool, limit int) ([]models.Order, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be greater than zero", er.ErrInvalidData)
+	}
+	return r.db.ListExpiredOrders(ctx, before, deleted, limit)
+}
+
+// PurgeOrders безвозвратно удаляет заказы со всеми связанными данными и возвращает число удаленных
+func (r *Repository) PurgeOrders(ctx context.Context, orderUIDs []string) (int, error) {
+	if len(orderUIDs) == 0 {
+		return 0, nil
+	}
+	return r.db.PurgeOrders(ctx, orderUIDs)
+}
+
+// GetStatusHistories возвращает истории статусов заказов по order_uid
+func (r *Repository) GetStatusHistories(ctx context.Context, orderUIDs []string) (map[string][]models.StatusChange, error) {
+	if len(orderUIDs) == 0 {
+		return nil, nil
+	}
+	return r.db.GetStatusHistories(ctx, orderUIDs)
+}
+
+// GetRawOrdersByUIDs возвращает исходные сообщения заказов по order_uid в порядке сохранения
+func (r *Repository) GetRawOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.RawOrder, error) {
+	if len(orderUIDs) == 0 {
+		return nil, nil
+	}
+	return r.db.GetRawOrdersByUIDs(ctx, orderUIDs)
+}
+
+// ImportOrder создает заказ, вынесенный из БД (например, в архив), вместе с историей статусов
+// и исходными сообщениями
+func (r *Repository) ImportOrder(ctx context.Context, order models.Order, history []models.StatusChange, raws []models.RawOrder) error {
+	return r.db.ImportOrder(ctx, order, history, raws)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"l0/internal/models"
+	"time"
+)
+
+// salesDefaultRange - период аналитики, если from не указан
+const salesDefaultRange = 30 * 24 * time.Hour
+
+// SalesReport строит временные ряды продаж по разрезу. Без to период заканчивается
+// текущими сутками, без from - начинается за 30 дней до to.
+// Периоды без заказов возвращаются нулевыми точками.
+func (s *Service) SalesReport(ctx context.Context, query models.SalesQuery) (models.SalesReport, error) {
+	if query.To.IsZero() {
+		query.To = time.Now().UTC()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-salesDefaultRange)
+	}
+	query.RoundToDays()
+	if err := query.Validate(); err != nil {
+		return models.SalesReport{}, err
+	}
+
+	rows, err := s.repo.SalesStats(ctx, query)
+	if err != nil {
+		return models.SalesReport{}, err
+	}
+
+	report := models.SalesReport{
+		Dimension: query.Dimension,
+		Interval:  query.Interval,
+		From:      query.From,
+		To:        query.To,
+		Series:    []models.SalesSeries{},
+	}
+	// Строки упорядочены по значению разреза, валюте и периоду
+	for i := 0; i < len(rows); {
+		series := models.SalesSeries{Key: rows[i].Key, Currency: rows[i].Currency}
+		for period := query.Interval.Truncate(query.From); period.Before(query.To); period = query.Interval.Next(period) {
+			point := models.SalesPoint{Period: period}
+			if i < len(rows) && rows[i].Key == series.Key && rows[i].Currency == series.Currency && rows[i].Period.Equal(period) {
+				point.Orders, point.Revenue, point.DeliveryCost = rows[i].Orders, rows[i].Revenue, rows[i].DeliveryCost
+				point.AverageBasket = averageBasket(rows[i].Revenue, rows[i].Orders)
+				i++
+			}
+			series.Points = append(series.Points, point)
+		}
+		// Строки вне сетки периодов не ожидаются, но не должны зациклить разбор
+		for i < len(rows) && rows[i].Key == series.Key && rows[i].Currency == series.Currency {
+			i++
+		}
+		report.Series = append(report.Series, series)
+	}
+	return report, nil
+}
+
+// averageBasket возвращает среднюю сумму заказа, округленную до минимальной единицы валюты
+func averageBasket(revenue, orders int64) int64 {
+	if orders == 0 {
+		return 0
+	}
+	return (revenue + orders/2) / orders
+}
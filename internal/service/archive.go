@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+)
+
+// errArchiveDisabled - архив не настроен (пустой ARCHIVE_DIR)
+var errArchiveDisabled = errors.New("archive is not configured, set ARCHIVE_DIR")
+
+// ArchiveOrders переносит заказы, созданные раньше before, из БД в архив
+func (s *Service) ArchiveOrders(ctx context.Context, before time.Time) (int, error) {
+	if s.archive == nil {
+		return 0, errArchiveDisabled
+	}
+	return s.archive.MoveOrders(ctx, s.repo, before, s.cfg.Retention.BatchSize, func(orderUIDs []string) {
+		s.cacheEvict(orderUIDs...)
+	})
+}
+
+// RestoreArchivedOrder возвращает заказ из архива в БД и кеш
+func (s *Service) RestoreArchivedOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	if s.archive == nil {
+		return nil, fmt.Errorf("%w: %v", er.ErrOrderNotFound, errArchiveDisabled)
+	}
+	if _, err := s.archive.RestoreOrder(ctx, s.repo, orderUID); err != nil {
+		return nil, err
+	}
+	return s.refreshCache(ctx, orderUID)
+}
+
+// getArchivedEntry читает заказ из архива. Архивные заказы не кешируются.
+func (s *Service) getArchivedEntry(orderUID string) (cacheEntry, error) {
+	if s.archive == nil {
+		return cacheEntry{}, fmt.Errorf("order retrieval error: %w", er.ErrOrderNotFound)
+	}
+	order, err := s.archive.Get(orderUID)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	return s.newCacheEntry(&order)
+}
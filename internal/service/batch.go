@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"l0/internal/repository"
+	"l0/pkg/er"
+	"slices"
+)
+
+// MaxBatchGetSize - наибольшее число заказов в одном пакетном запросе
+const MaxBatchGetSize = 100
+
+// BatchGetOrders возвращает заказы в порядке запроса. Заказы из кеша отдаются сразу,
+// остальные загружаются из БД одним запросом и кладутся в кеш; ненайденные
+// ищутся в архиве и помечаются BatchOrderNotFound.
+func (s *Service) BatchGetOrders(ctx context.Context, orderUIDs []string) ([]models.BatchOrderResult, error) {
+	if len(orderUIDs) == 0 {
+		return nil, fmt.Errorf("%w: order_uids cannot be empty", er.ErrInvalidData)
+	}
+	if len(orderUIDs) > MaxBatchGetSize {
+		return nil, fmt.Errorf("%w: at most %d order_uids per request", er.ErrInvalidData, MaxBatchGetSize)
+	}
+
+	for _, orderUID := range orderUIDs {
+		if orderUID == "" {
+			return nil, fmt.Errorf("%w: order_uid cannot be empty", er.ErrInvalidData)
+		}
+	}
+
+	entries, err := s.getEntries(ctx, orderUIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, orderUID := range orderUIDs {
+		if _, ok := entries[orderUID]; ok {
+			continue
+		}
+		entry, err := s.getArchivedEntry(orderUID)
+		if errors.Is(err, er.ErrOrderNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries[orderUID] = entry
+	}
+
+	results := make([]models.BatchOrderResult, len(orderUIDs))
+	for i, orderUID := range orderUIDs {
+		results[i] = models.BatchOrderResult{OrderUID: orderUID, Status: models.BatchOrderNotFound}
+		if entry, ok := entries[orderUID]; ok {
+			results[i].Status = models.BatchOrderFound
+			results[i].Order = entry.responseJSON()
+		}
+	}
+	return results, nil
+}
+
+// getEntries возвращает записи кеша найденных заказов по order_uid. Заказы из кеша
+// берутся сразу, остальные загружаются из основной БД одним запросом и кладутся в кеш.
+func (s *Service) getEntries(ctx context.Context, orderUIDs []string) (map[string]cacheEntry, error) {
+	entries := make(map[string]cacheEntry, len(orderUIDs))
+	var misses []string
+	s.mu.RLock()
+	for _, orderUID := range orderUIDs {
+		if _, ok := entries[orderUID]; ok {
+			continue
+		}
+		if entry, ok := s.cache[orderUID]; ok {
+			entries[orderUID] = entry
+		} else if !slices.Contains(misses, orderUID) {
+			misses = append(misses, orderUID)
+		}
+	}
+	s.mu.RUnlock()
+	if len(misses) == 0 {
+		return entries, nil
+	}
+
+	// Как и в getEntry, промахи читаются из основной БД. Между чтением кеша и записью заказ мог
+	// обновиться или быть удален, поэтому записи кладутся через cacheStoreLocked.
+	seq := s.beginCacheLoad()
+	defer s.endCacheLoad()
+	orders, err := s.repo.GetOrdersByUIDs(repository.WithPrimary(ctx), misses)
+	if err != nil {
+		return nil, err
+	}
+	fetched := make([]cacheEntry, len(orders))
+	for i := range orders {
+		if fetched[i], err = s.newCacheEntry(&orders[i]); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	for i, order := range orders {
+		entries[order.OrderUID] = s.cacheStoreLocked(order.OrderUID, fetched[i], seq)
+	}
+	s.mu.Unlock()
+	return entries, nil
+}
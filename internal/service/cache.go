@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"l0/internal/models"
+	"l0/internal/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// cacheEntry - неизменяемое представление заказа в кеше.
+// Заказ хранится в сериализованном виде, поэтому изменения объекта
+// вызывающим кодом не затрагивают содержимое кеша.
+type cacheEntry struct {
+	order    []byte // JSON models.Order
+	response []byte // заранее подготовленный JSON models.OrderResponse
+	version  int64  // версия заказа для ETag и сверки снимка кеша
+}
+
+// newCacheEntry сериализует заказ и его безопасное представление
+func (s *Service) newCacheEntry(order *models.Order) (cacheEntry, error) {
+	orderJSON, err := json.Marshal(order)
+	if err != nil {
+		return cacheEntry{}, fmt.Errorf("failed to marshal order %s: %w", order.OrderUID, err)
+	}
+	responseJSON, err := json.Marshal(s.convertToOrderResponse(order))
+	if err != nil {
+		return cacheEntry{}, fmt.Errorf("failed to marshal order response %s: %w", order.OrderUID, err)
+	}
+	return cacheEntry{order: orderJSON, response: responseJSON, version: order.Version}, nil
+}
+
+// beginCacheLoad отмечает начало чтения заказов из БД для кеша и возвращает номер последнего
+// вытеснения. Каждый вызов завершается endCacheLoad.
+func (s *Service) beginCacheLoad() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	return s.evictSeq
+}
+
+// endCacheLoad отмечает конец чтения. Когда чтений нет, номера вытеснений больше не нужны.
+func (s *Service) endCacheLoad() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads--
+	if s.loads == 0 {
+		clear(s.evicted)
+	}
+}
+
+// cacheStoreLocked кладет в кеш запись, прочитанную из БД чтением, начатым после вытеснения seq,
+// и возвращает запись, которую нужно отдать. Заказ, вытесненный после начала чтения (удаленный,
+// пока шло чтение), в кеш не возвращается. Если в кеше уже та же или более новая версия,
+// остается она. Вызывается под s.mu.
+func (s *Service) cacheStoreLocked(orderUID string, entry cacheEntry, seq uint64) cacheEntry {
+	if s.evicted[orderUID] > seq {
+		return entry
+	}
+	if cached, ok := s.cache[orderUID]; ok && cached.version >= entry.version {
+		return cached
+	}
+	s.cache[orderUID] = entry
+	return entry
+}
+
+// cacheStore кладет в кеш запись, прочитанную из БД, по правилам cacheStoreLocked
+func (s *Service) cacheStore(orderUID string, entry cacheEntry, seq uint64) cacheEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cacheStoreLocked(orderUID, entry, seq)
+}
+
+// cacheEvict удаляет заказы из кеша
+func (s *Service) cacheEvict(orderUIDs ...string) {
+	s.mu.Lock()
+	s.evictLocked(orderUIDs)
+	s.mu.Unlock()
+}
+
+// evictLocked удаляет заказы из кеша. Пока идут чтения из БД, запоминает номер вытеснения,
+// чтобы чтение, начатое раньше, не вернуло заказ в кеш. Вызывается под s.mu.
+func (s *Service) evictLocked(orderUIDs []string) {
+	s.evictSeq++
+	for _, orderUID := range orderUIDs {
+		delete(s.cache, orderUID)
+		if s.loads > 0 {
+			s.evicted[orderUID] = s.evictSeq
+		}
+	}
+}
+
+// refreshCache перечитывает заказ из основной БД после записи и обновляет его запись в кеше
+func (s *Service) refreshCache(ctx context.Context, orderUID string) (*models.Order, error) {
+	seq := s.beginCacheLoad()
+	defer s.endCacheLoad()
+
+	order, err := s.repo.GetOrder(repository.WithPrimary(ctx), orderUID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.newCacheEntry(&order)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheStore(orderUID, entry, seq)
+	return &order, nil
+}
+
+// reconcileOverlap - насколько раньше метки прошлой сверки читаются изменения. updated_at - время
+// начала транзакции, поэтому транзакция, зафиксированная после сверки, могла начаться до метки.
+const reconcileOverlap = time.Minute
+
+// ReconcileCache сверяет кеш с основной БД по заказам, изменившимся после прошлой сверки:
+// удаленные и перенесенные в архив (в том числе другим процессом) убираются из кеша,
+// а новые и измененные загружаются одним запросом. Первая сверка сравнивает версии всех заказов.
+func (s *Service) ReconcileCache(ctx context.Context) error {
+	ctx = repository.WithPrimary(ctx)
+	seq := s.beginCacheLoad()
+	defer s.endCacheLoad()
+
+	s.mu.RLock()
+	since := s.reconciledAt
+	s.mu.RUnlock()
+	if !since.IsZero() {
+		since = since.Add(-reconcileOverlap)
+	}
+	changes, err := s.repo.GetOrderChanges(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	var stale, dropped []string
+	s.mu.RLock()
+	for orderUID, version := range changes.Versions {
+		if entry, ok := s.cache[orderUID]; !ok || entry.version != version {
+			stale = append(stale, orderUID)
+		}
+	}
+	if since.IsZero() {
+		for orderUID := range s.cache {
+			if _, ok := changes.Versions[orderUID]; !ok {
+				dropped = append(dropped, orderUID)
+			}
+		}
+	} else {
+		for _, orderUID := range changes.Removed {
+			// Заказ мог быть удален и создан заново после since
+			if _, active := changes.Versions[orderUID]; !active && s.cache[orderUID].order != nil {
+				dropped = append(dropped, orderUID)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	orders, err := s.repo.GetOrdersByUIDs(ctx, stale)
+	if err != nil {
+		return err
+	}
+	entries := make([]cacheEntry, len(orders))
+	for i := range orders {
+		if entries[i], err = s.newCacheEntry(&orders[i]); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.evictLocked(dropped)
+	for i, order := range orders {
+		// Запись могла обновиться после чтения версий, более новую не перезаписываем
+		s.cacheStoreLocked(order.OrderUID, entries[i], seq)
+	}
+	if changes.Watermark.After(s.reconciledAt) {
+		s.reconciledAt = changes.Watermark
+	}
+	s.mu.Unlock()
+
+	if len(orders) > 0 || len(dropped) > 0 {
+		zap.S().Infof("cache reconciled: %d orders reloaded, %d dropped", len(orders), len(dropped))
+	}
+	return nil
+}
+
+// RunCacheReconcile периодически сверяет кеш с БД до отмены контекста
+func (s *Service) RunCacheReconcile(ctx context.Context) {
+	if s.cfg.ReconcileInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ReconcileCache(ctx); err != nil {
+				zap.S().Errorf("failed to reconcile cache: %v", err)
+			}
+		}
+	}
+}
+
+// decodeOrder возвращает новую копию заказа из кеша
+func (e cacheEntry) decodeOrder() (*models.Order, error) {
+	var order models.Order
+	if err := json.Unmarshal(e.order, &order); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached order: %w", err)
+	}
+	return &order, nil
+}
+
+// decodeResponse возвращает новую копию безопасного представления заказа из кеша
+func (e cacheEntry) decodeResponse() (*models.OrderResponse, error) {
+	var resp models.OrderResponse
+	if err := json.Unmarshal(e.response, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached order response: %w", err)
+	}
+	return &resp, nil
+}
+
+// responseJSON возвращает копию подготовленного JSON ответа
+func (e cacheEntry) responseJSON() []byte {
+	return append([]byte(nil), e.response...)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"strings"
+)
+
+// favoriteBrandsLimit - сколько любимых брендов возвращается в сводке покупателя
+const favoriteBrandsLimit = 5
+
+// GetCustomerOrders возвращает страницу заказов покупателя, а для первой страницы - и сводку по ним
+func (s *Service) GetCustomerOrders(ctx context.Context, customerID, cursor string, limit int) (*models.CustomerOrdersResponse, error) {
+	if strings.TrimSpace(customerID) == "" {
+		return nil, fmt.Errorf("%w: customer_id is required", er.ErrInvalidData)
+	}
+
+	page, err := s.ListOrders(ctx, models.OrderFilter{CustomerID: customerID}, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.CustomerOrdersResponse{Items: page.Items, NextCursor: page.NextCursor}
+	if cursor != "" {
+		return resp, nil
+	}
+
+	summary, err := s.repo.GetCustomerSummary(ctx, customerID, favoriteBrandsLimit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range summary.TotalSpent {
+		spent := &summary.TotalSpent[i]
+		spent.AmountFormatted = spent.Currency.Format(spent.Amount)
+	}
+	resp.Summary = &summary
+	return resp, nil
+}
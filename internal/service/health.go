@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"l0/internal/repository"
+)
+
+// Health - состояние сервиса для проверки здоровья
+type Health struct {
+	Database     repository.Health `json:"database"`
+	CachedOrders int               `json:"cached_orders"`
+}
+
+// Healthy сообщает, может ли сервис обслуживать запросы
+func (h Health) Healthy() bool {
+	return h.Database.Healthy
+}
+
+// Health проверяет хранилище и возвращает состояние сервиса
+func (s *Service) Health(ctx context.Context) Health {
+	s.mu.RLock()
+	cached := len(s.cache)
+	s.mu.RUnlock()
+
+	return Health{
+		Database:     s.repo.Health(ctx),
+		CachedOrders: cached,
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"l0/internal/models"
+	"l0/internal/repository"
+	"l0/pkg/er"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultIdempotencyTTL - срок хранения ключей идемпотентности, если он не задан в конфиге
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyCleanupInterval - как часто удаляются истекшие ключи идемпотентности
+const idempotencyCleanupInterval = time.Hour
+
+// forwardedOrdersClient - клиент ключей идемпотентности, которыми ReserveNewOrder занимает order_uid
+// заказов, пересылаемых в брокер. Имя партнера из INGEST_TOKENS не может содержать двоеточие,
+// поэтому эти ключи не пересекаются с ключами партнеров.
+const forwardedOrdersClient = ":forwarded-orders"
+
+// idempotencyLease - сколько ключ без сохраненного ответа считается занятым обрабатываемым запросом.
+// Если процесс упал посреди обработки, по истечении этого срока запрос можно повторить с тем же ключом.
+const idempotencyLease = 5 * time.Minute
+
+// SubmitOrder создает заказ, принятый в обход Kafka, вместе с исходным сообщением в одной транзакции.
+// В отличие от IngestOrder не перезаписывает существующий заказ, а возвращает er.ErrOrderExists.
+func (s *Service) SubmitOrder(ctx context.Context, order *models.Order, raw models.RawOrder) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+	created := newOrder(order)
+	created.Version = 1
+	entry, err := s.newCacheEntry(&created)
+	if err != nil {
+		return err
+	}
+	raw.OrderUID = created.OrderUID
+	if err := s.repo.CreateOrderWithRaw(ctx, created, raw); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.cache[created.OrderUID] = entry
+	s.mu.Unlock()
+	return nil
+}
+
+// ReserveNewOrder проверяет заказ перед пересылкой в брокер и занимает его order_uid. Consumer
+// перезаписывает существующие заказы, поэтому повтор order_uid отклоняется здесь с er.ErrOrderExists,
+// как и при записи в БД. Пока consumer не сохранил заказ, order_uid занят ключом в таблице ключей
+// идемпотентности: ключ занимается атомарно, поэтому из одновременных запросов с одним order_uid
+// в брокер уходит только один. Если заказ не удалось отправить, order_uid освобождает ReleaseNewOrder.
+func (s *Service) ReserveNewOrder(ctx context.Context, order *models.Order) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	_, cached := s.cache[order.OrderUID]
+	s.mu.RUnlock()
+	if cached {
+		return fmt.Errorf("order forwarding error: %w", er.ErrOrderExists)
+	}
+	_, err := s.repo.GetOrder(repository.WithPrimary(ctx), order.OrderUID)
+	if err == nil {
+		return fmt.Errorf("order forwarding error: %w", er.ErrOrderExists)
+	}
+	if !errors.Is(err, er.ErrOrderNotFound) {
+		return err
+	}
+
+	// Ключ держится весь срок хранения ключей идемпотентности, а не lease: за это время
+	// consumer успевает сохранить заказ, и дальше повтор отклоняет проверка выше
+	ttl := s.idempotencyTTL()
+	_, reserved, err := s.repo.ReserveIdempotencyKey(ctx, models.IdempotencyKey{
+		Client: forwardedOrdersClient,
+		Key:    order.OrderUID,
+	}, ttl, ttl)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return fmt.Errorf("order forwarding error: %w", er.ErrOrderExists)
+	}
+	return nil
+}
+
+// ReleaseNewOrder освобождает order_uid, занятый ReserveNewOrder, если заказ не удалось отправить в брокер
+func (s *Service) ReleaseNewOrder(ctx context.Context, orderUID string) error {
+	return s.repo.ReleaseIdempotencyKey(ctx, forwardedOrdersClient, orderUID)
+}
+
+func (s *Service) idempotencyTTL() time.Duration {
+	if s.cfg.IdempotencyTTL <= 0 {
+		return DefaultIdempotencyTTL
+	}
+	return s.cfg.IdempotencyTTL
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности клиента для запроса с телом body.
+// Если ключ уже использовался для того же запроса, возвращает сохраненный ответ, который нужно
+// отдать вместо повторной обработки; nil означает, что ключ занят и запрос нужно обработать.
+func (s *Service) ReserveIdempotencyKey(ctx context.Context, client, key string, body []byte) (*models.IdempotencyKey, error) {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	existing, reserved, err := s.repo.ReserveIdempotencyKey(ctx, models.IdempotencyKey{
+		Client:      client,
+		Key:         key,
+		RequestHash: hash,
+	}, s.idempotencyTTL(), idempotencyLease)
+	if err != nil || reserved {
+		return nil, err
+	}
+	if existing.RequestHash != hash {
+		return nil, er.ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode == 0 {
+		return nil, er.ErrIdempotencyKeyInProgress
+	}
+	return &existing, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности
+func (s *Service) CompleteIdempotencyKey(ctx context.Context, client, key string, statusCode int, response []byte) error {
+	return s.repo.CompleteIdempotencyKey(ctx, client, key, statusCode, response)
+}
+
+// ReleaseIdempotencyKey освобождает ключ после неудачной обработки, чтобы запрос можно было повторить
+func (s *Service) ReleaseIdempotencyKey(ctx context.Context, client, key string) error {
+	return s.repo.ReleaseIdempotencyKey(ctx, client, key)
+}
+
+// RunIdempotencyCleanup периодически удаляет истекшие ключи идемпотентности до отмены контекста
+func (s *Service) RunIdempotencyCleanup(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.repo.PurgeIdempotencyKeys(ctx, s.idempotencyTTL())
+		if err != nil && ctx.Err() == nil {
+			zap.S().Errorf("failed to purge idempotency keys: %v", err)
+		}
+		if purged > 0 {
+			zap.S().Infof("purged %d expired idempotency keys", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"l0/internal/models"
+	"l0/internal/repository"
+	"l0/pkg/er"
+	"strconv"
+	"time"
+)
+
+// PatchOrder применяет к заказу JSON merge patch (RFC 7396). Менять можно только доставку
+// (поля объединяются), товары (непустой список заменяется целиком, goods_total и amount платежа
+// пересчитываются) и статус (по разрешенным переходам). Изменения сохраняются одной транзакцией.
+// Если expectedVersion не 0, заказ изменяется только при совпадении версии.
+func (s *Service) PatchOrder(ctx context.Context, orderUID string, patch []byte, expectedVersion int64) (*models.Order, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%w: patch must be a JSON object", er.ErrInvalidData)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: patch is empty", er.ErrInvalidData)
+	}
+
+	order, err := s.repo.GetOrder(repository.WithPrimary(ctx), orderUID)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && order.Version != expectedVersion {
+		return nil, fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, order.Version)
+	}
+
+	var change *models.StatusChange
+	var dataChanged bool
+	for name, value := range fields {
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			return nil, fmt.Errorf("%w: %s cannot be removed", er.ErrInvalidData, name)
+		}
+		switch name {
+		case "delivery":
+			if order.Delivery, err = mergePatch(order.Delivery, value); err != nil {
+				return nil, fmt.Errorf("%w: delivery: %v", er.ErrInvalidData, err)
+			}
+			dataChanged = true
+		case "items":
+			var items models.Items
+			if err := decodeStrict(value, &items); err != nil {
+				return nil, fmt.Errorf("%w: items: %v", er.ErrInvalidData, err)
+			}
+			if err := patchItems(&order, items); err != nil {
+				return nil, err
+			}
+			dataChanged = true
+		case "status":
+			to, err := parsePatchStatus(value)
+			if err != nil {
+				return nil, err
+			}
+			if to == order.Status {
+				continue
+			}
+			if !order.Status.CanTransitionTo(to) {
+				return nil, fmt.Errorf("%w: %s -> %s", er.ErrInvalidStatusTransition, order.Status, to)
+			}
+			change = &models.StatusChange{OrderUID: orderUID, From: order.Status, To: to, ChangedAt: time.Now().UTC()}
+		default:
+			return nil, fmt.Errorf("%w: field %q cannot be patched, only delivery, items and status", er.ErrInvalidData, name)
+		}
+	}
+
+	if !dataChanged {
+		if change == nil {
+			return &order, nil
+		}
+		// Только смена статуса: не создаем пустую запись об изменении данных
+		return s.ChangeOrderStatus(ctx, orderUID, change.To, "", order.Version)
+	}
+
+	order.NormalizeStatus()
+	if err := s.repo.PatchOrder(ctx, order, change, order.Version); err != nil {
+		return nil, err
+	}
+	return s.refreshCache(ctx, orderUID)
+}
+
+// CancelOrder отменяет заказ. Повторная отмена возвращает заказ без изменений.
+// Если expectedVersion не 0, заказ отменяется только при совпадении версии.
+func (s *Service) CancelOrder(ctx context.Context, orderUID, comment string, expectedVersion int64) (*models.Order, error) {
+	order, err := s.repo.GetOrder(repository.WithPrimary(ctx), orderUID)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && order.Version != expectedVersion {
+		return nil, fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, order.Version)
+	}
+	if order.Status == models.StatusCancelled {
+		return &order, nil
+	}
+	return s.ChangeOrderStatus(ctx, orderUID, models.StatusCancelled, comment, order.Version)
+}
+
+// patchItems заменяет товары заказа и пересчитывает суммы платежа: goods_total - сумма total_price
+// товаров, amount меняется на ту же разницу, стоимость доставки и пошлина остаются прежними
+func patchItems(order *models.Order, items models.Items) error {
+	if len(items) == 0 {
+		return fmt.Errorf("%w: items cannot be empty", er.ErrInvalidData)
+	}
+	var goodsTotal int64
+	for i := range items {
+		if err := items[i].Validate(); err != nil {
+			return fmt.Errorf("item %d: %w", i+1, err)
+		}
+		goodsTotal += items[i].TotalPrice
+	}
+	amount := order.Payment.Amount + goodsTotal - order.Payment.GoodsTotal
+	if amount <= 0 {
+		return fmt.Errorf("%w: payment amount must be greater than zero", er.ErrInvalidData)
+	}
+	order.Items = items
+	order.Payment.GoodsTotal = goodsTotal
+	order.Payment.Amount = amount
+	return nil
+}
+
+// parsePatchStatus разбирает статус из патча: имя ("shipped") или код (400)
+func parsePatchStatus(value json.RawMessage) (models.Status, error) {
+	var name string
+	if err := json.Unmarshal(value, &name); err == nil {
+		return models.ParseStatus(name)
+	}
+	var code int
+	if err := json.Unmarshal(value, &code); err == nil {
+		return models.ParseStatus(strconv.Itoa(code))
+	}
+	return 0, fmt.Errorf("%w: status must be a status name or code", er.ErrInvalidData)
+}
+
+// mergePatch применяет JSON merge patch к копии v. Поля со значением null сбрасываются,
+// неизвестные поля считаются ошибкой.
+func mergePatch[T any](v T, patch json.RawMessage) (T, error) {
+	var target, changes any
+	doc, err := json.Marshal(v)
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return v, err
+	}
+	merged, err := json.Marshal(applyMergePatch(target, changes))
+	if err != nil {
+		return v, err
+	}
+	var result T
+	if err := decodeStrict(merged, &result); err != nil {
+		return v, err
+	}
+	return result, nil
+}
+
+// applyMergePatch объединяет patch с target по правилам RFC 7396
+func applyMergePatch(target, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	doc, ok := target.(map[string]any)
+	if !ok {
+		doc = make(map[string]any)
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(doc, name)
+			continue
+		}
+		doc[name] = applyMergePatch(doc[name], value)
+	}
+	return doc
+}
+
+// decodeStrict разбирает JSON, не допуская неизвестных полей
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
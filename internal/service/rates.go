@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"os"
+	"time"
+)
+
+// ImportExchangeRates сохраняет курсы валют, заменяя уже загруженные на те же даты
+func (s *Service) ImportExchangeRates(ctx context.Context, rates []models.ExchangeRate) (int, error) {
+	return s.repo.SaveExchangeRates(ctx, rates)
+}
+
+// ImportRatesFile загружает курсы из CSV файла в формате date,currency,rate
+func (s *Service) ImportRatesFile(ctx context.Context, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open rates file: %w", err)
+	}
+	defer f.Close()
+
+	rates, err := models.ParseExchangeRatesCSV(f)
+	if err != nil {
+		return 0, fmt.Errorf("rates file %s: %w", path, err)
+	}
+	return s.ImportExchangeRates(ctx, rates)
+}
+
+// paymentDate возвращает дату, на которую пересчитывается сумма платежа:
+// дату оплаты, а если она не указана - дату создания заказа
+func paymentDate(order *models.Order) time.Time {
+	if order.Payment.PaymentDt > 0 {
+		return time.Unix(int64(order.Payment.PaymentDt), 0).UTC()
+	}
+	return order.DateCreated.UTC()
+}
+
+// crossRateDate возвращает дату, с которой действует кросс-курс двух курсов: более позднюю из дат
+// их загрузки. Курс базовой валюты не загружается и на дату не влияет; если обе валюты базовые - дата платежа.
+func crossRateDate(date time.Time, rates ...models.ExchangeRate) time.Time {
+	var rateDate time.Time
+	for _, rate := range rates {
+		if rate.Currency != models.RateBaseCurrency && rate.Date.After(rateDate) {
+			rateDate = rate.Date
+		}
+	}
+	if rateDate.IsZero() {
+		return date.Truncate(24 * time.Hour)
+	}
+	return rateDate
+}
+
+// ConvertPayment пересчитывает сумму платежа заказа в валюту to по курсам на дату платежа
+func (s *Service) ConvertPayment(ctx context.Context, order *models.Order, to models.Currency) (models.ConvertedAmount, error) {
+	date := paymentDate(order)
+	fromRate, err := s.repo.GetExchangeRate(ctx, order.Payment.Currency, date)
+	if err != nil {
+		return models.ConvertedAmount{}, err
+	}
+	toRate, err := s.repo.GetExchangeRate(ctx, to, date)
+	if err != nil {
+		return models.ConvertedAmount{}, err
+	}
+
+	from, _ := fromRate.Value()
+	target, _ := toRate.Value()
+	converted, err := order.Payment.Currency.Convert(order.Payment.Amount, to, from, target)
+	if err != nil {
+		return models.ConvertedAmount{}, err
+	}
+
+	return models.ConvertedAmount{
+		Currency:        to,
+		Amount:          converted,
+		AmountFormatted: to.Format(converted),
+		Rate:            from.Quo(from, target).FloatString(6),
+		RateDate:        crossRateDate(date, fromRate, toRate),
+	}, nil
+}
+
+// GetOrderResponseConverted возвращает безопасную версию заказа с суммой платежа в валютах currencies
+func (s *Service) GetOrderResponseConverted(ctx context.Context, orderUID string, currencies []models.Currency) (*models.OrderResponse, error) {
+	entry, err := s.getEntry(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := entry.decodeOrder()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := entry.decodeResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Converted = make([]models.ConvertedAmount, 0, len(currencies))
+	for _, currency := range currencies {
+		converted, err := s.ConvertPayment(ctx, order, currency)
+		if err != nil {
+			return nil, err
+		}
+		resp.Converted = append(resp.Converted, converted)
+	}
+	return resp, nil
+}
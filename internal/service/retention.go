@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Режимы политики хранения
+const (
+	RetentionModeDelete  = "delete"  // устаревшие заказы удаляются безвозвратно
+	RetentionModeArchive = "archive" // устаревшие заказы переносятся в архив (ARCHIVE_DIR)
+)
+
+// RetentionConfig - политика хранения заказов. Нулевой MaxAge отключает очистку.
+type RetentionConfig struct {
+	MaxAge    time.Duration `env:"RETENTION_MAX_AGE"`
+	Interval  time.Duration `env:"RETENTION_INTERVAL" envDefault:"1h"`
+	Mode      string        `env:"RETENTION_MODE" envDefault:"delete"`
+	BatchSize int           `env:"RETENTION_BATCH_SIZE" envDefault:"500"`
+}
+
+// ApplyRetention очищает заказы старше MaxAge. Мягко удаленные заказы удаляются безвозвратно,
+// остальные удаляются или переносятся в архив в зависимости от режима.
+// Возвращает число очищенных заказов.
+func (s *Service) ApplyRetention(ctx context.Context) (int, error) {
+	cfg := s.cfg.Retention
+	if cfg.MaxAge <= 0 {
+		return 0, nil
+	}
+	before := time.Now().Add(-cfg.MaxAge)
+
+	switch cfg.Mode {
+	case RetentionModeDelete:
+		deleted, err := s.purgeExpired(ctx, before, true)
+		if err != nil {
+			return deleted, err
+		}
+		purged, err := s.purgeExpired(ctx, before, false)
+		return deleted + purged, err
+	case RetentionModeArchive:
+		deleted, err := s.purgeExpired(ctx, before, true)
+		if err != nil {
+			return deleted, err
+		}
+		archived, err := s.ArchiveOrders(ctx, before)
+		return deleted + archived, err
+	default:
+		return 0, fmt.Errorf("unknown retention mode %q", cfg.Mode)
+	}
+}
+
+// purgeExpired безвозвратно удаляет заказы, созданные раньше before, пачками по BatchSize
+func (s *Service) purgeExpired(ctx context.Context, before time.Time, deleted bool) (int, error) {
+	batchSize := s.cfg.Retention.BatchSize
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("%w: retention batch size must be greater than zero", er.ErrInvalidData)
+	}
+
+	var total int
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		orders, err := s.repo.ListExpiredOrders(ctx, before, deleted, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(orders) == 0 {
+			return total, nil
+		}
+
+		uids := make([]string, len(orders))
+		for i := range orders {
+			uids[i] = orders[i].OrderUID
+		}
+		purged, err := s.repo.PurgeOrders(ctx, uids)
+		if err != nil {
+			return total, err
+		}
+		s.cacheEvict(uids...)
+		total += purged
+
+		if len(orders) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunRetention периодически применяет политику хранения до отмены контекста
+func (s *Service) RunRetention(ctx context.Context) {
+	if s.cfg.Retention.MaxAge <= 0 || s.cfg.Retention.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Retention.Interval)
+	defer ticker.Stop()
+
+	ctx = models.WithAuditSource(ctx, models.AuditSource{Kind: models.AuditSourceSystem, Actor: "retention"})
+
+	for {
+		purged, err := s.ApplyRetention(ctx)
+		if err != nil && ctx.Err() == nil {
+			zap.S().Errorf("failed to apply retention policy: %v", err)
+		}
+		if purged > 0 {
+			zap.S().Infof("retention policy purged %d orders", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,94 +2,419 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"l0/internal/archive"
 	"l0/internal/models"
 	"l0/internal/repository"
+	"l0/pkg/er"
+	"slices"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Ограничения размера страницы списка заказов
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
 )
 
+type Config struct {
+	SnapshotPath     string        `env:"CACHE_SNAPSHOT_PATH"`
+	SnapshotInterval time.Duration `env:"CACHE_SNAPSHOT_INTERVAL" envDefault:"1m"`
+	Retention        RetentionConfig
+	Archive          archive.Config
+	// RatesFile - CSV с курсами валют (date,currency,rate), загружается при старте
+	RatesFile string `env:"RATES_FILE"`
+	// IdempotencyTTL - сколько хранятся ключи идемпотентности HTTP API
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+	// ReconcileInterval - как часто кеш сверяется с БД по версиям заказов (0 - не сверять)
+	ReconcileInterval time.Duration `env:"CACHE_RECONCILE_INTERVAL" envDefault:"5m"`
+}
+
 type Service struct {
-	repo  *repository.Repository
-	cache map[string]*models.Order // [order_uid]Order
-	mu    sync.RWMutex
+	cfg     Config
+	repo    repository.OrderRepository
+	archive *archive.Archive      // nil, если архив не настроен
+	cache   map[string]cacheEntry // [order_uid]cacheEntry
+	// reconciledAt - время БД, до которого изменения заказов уже учтены в кеше (нулевое - не сверялся)
+	reconciledAt time.Time
+	// Число идущих чтений из БД для кеша и номера вытеснений order_uid за время этих чтений
+	loads    int
+	evictSeq uint64
+	evicted  map[string]uint64
+	mu       sync.RWMutex
 }
 
-func NewService(repo *repository.Repository) (*Service, error) {
+func NewService(cfg Config, repo repository.OrderRepository) (*Service, error) {
 	s := &Service{
-		repo:  repo,
-		cache: make(map[string]*models.Order),
+		cfg:     cfg,
+		repo:    repo,
+		cache:   make(map[string]cacheEntry),
+		evicted: make(map[string]uint64),
+	}
+
+	if cfg.Archive.Dir != "" {
+		arch, err := archive.Open(cfg.Archive.Dir)
+		if err != nil {
+			return nil, err
+		}
+		s.archive = arch
+	}
+
+	if cfg.RatesFile != "" {
+		n, err := s.ImportRatesFile(context.Background(), cfg.RatesFile)
+		if err != nil {
+			return nil, err
+		}
+		zap.S().Infof("loaded %d exchange rates from %s", n, cfg.RatesFile)
+	}
+
+	// Если есть снимок кеша, догружаем из БД только новые заказы
+	if cfg.SnapshotPath != "" {
+		err := s.restoreFromSnapshot(context.Background())
+		if err == nil {
+			return s, nil
+		}
+		zap.S().Warnf("failed to restore cache from snapshot, falling back to full restore: %v", err)
+		s.cache = make(map[string]cacheEntry)
 	}
+
 	if err := s.RestoreCache(context.Background()); err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
-// RestoreCache загружает все заказы из БД в кеш
+// RestoreCache загружает все заказы из основной БД в кеш полной сверкой,
+// которая заодно запоминает метку для следующих сверок
 func (s *Service) RestoreCache(ctx context.Context) error {
-	orders, err := s.repo.GetAllOrders(ctx)
-	if err != nil {
-		return err
-	}
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	for _, order := range orders {
-		orderCopy := order
-		s.cache[order.OrderUID] = &orderCopy
-	}
-	return nil
+	s.reconciledAt = time.Time{}
+	s.mu.Unlock()
+	return s.ReconcileCache(ctx)
 }
 
-// GetOrder возвращает заказ по ID (сначала из кеша, если нет — из БД)
-func (s *Service) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+// getEntry возвращает запись кеша по ID (если ее нет — загружает заказ из БД, а затем из архива)
+func (s *Service) getEntry(ctx context.Context, orderUID string) (cacheEntry, error) {
 	s.mu.RLock()
-	order, ok := s.cache[orderUID]
+	entry, ok := s.cache[orderUID]
 	s.mu.RUnlock()
 	if ok {
-		return order, nil
+		return entry, nil
+	}
+	// Кеш живет без TTL, поэтому промахи читаются из основной БД: реплика могла еще не получить
+	// удаление или изменение заказа
+	seq := s.beginCacheLoad()
+	defer s.endCacheLoad()
+	orderDB, err := s.repo.GetOrder(repository.WithPrimary(ctx), orderUID)
+	if errors.Is(err, er.ErrOrderNotFound) {
+		return s.getArchivedEntry(orderUID)
 	}
-	orderDB, err := s.repo.GetOrder(ctx, orderUID)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	entry, err = s.newCacheEntry(&orderDB)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	return s.cacheStore(orderUID, entry, seq), nil
+}
+
+// GetOrder возвращает копию заказа по ID (сначала из кеша, если нет — из БД)
+func (s *Service) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	entry, err := s.getEntry(ctx, orderUID)
 	if err != nil {
 		return nil, err
 	}
-	s.mu.Lock()
-	orderCopy := orderDB
-	s.cache[orderUID] = &orderCopy
-	s.mu.Unlock()
-	return &orderCopy, nil
+	return entry.decodeOrder()
 }
 
 // GetOrderResponse возвращает безопасную версию заказа для пользователя
 func (s *Service) GetOrderResponse(ctx context.Context, orderUID string) (*models.OrderResponse, error) {
-	order, err := s.GetOrder(ctx, orderUID)
+	entry, err := s.getEntry(ctx, orderUID)
 	if err != nil {
 		return nil, err
 	}
-	return s.convertToOrderResponse(order), nil
+	return entry.decodeResponse()
+}
+
+// GetOrderResponseJSON возвращает копию готового JSON безопасной версии заказа и версию заказа
+func (s *Service) GetOrderResponseJSON(ctx context.Context, orderUID string) ([]byte, int64, error) {
+	entry, err := s.getEntry(ctx, orderUID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entry.responseJSON(), entry.version, nil
+}
+
+// newOrder возвращает копию заказа со статусом, согласованным с товарами.
+// Заказ вызывающего кода не изменяется.
+func newOrder(order *models.Order) models.Order {
+	created := *order
+	created.Items = slices.Clone(order.Items)
+	created.NormalizeStatus()
+	return created
 }
 
 // CreateOrder сохраняет заказ в БД и кеш
 func (s *Service) CreateOrder(ctx context.Context, order *models.Order) error {
-	if err := s.repo.CreateOrder(ctx, *order); err != nil {
+	created := newOrder(order)
+	created.Version = 1
+	entry, err := s.newCacheEntry(&created)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateOrder(ctx, created); err != nil {
 		return err
 	}
 	s.mu.Lock()
-	s.cache[order.OrderUID] = order
+	s.cache[created.OrderUID] = entry
 	s.mu.Unlock()
 	return nil
 }
 
+// IngestOrder сохраняет исходное сообщение и применяет полученный из него заказ.
+// Исходное сообщение сохраняется до нормализации, чтобы его можно было изучить при ошибке.
+// Событие с event_version не новее уже примененной (повтор или пришедшее не по порядку) игнорируется.
+// Событие без event_version для существующего заказа считается повтором: раньше такое сообщение
+// отклонялось как дубликат, теперь пропускается без ошибки и не перезаписывает заказ.
+func (s *Service) IngestOrder(ctx context.Context, order *models.Order, raw models.RawOrder) error {
+	raw.OrderUID = order.OrderUID
+	ctx = models.WithAuditSource(ctx, raw.Source())
+	if err := s.repo.SaveRawOrder(ctx, raw); err != nil {
+		zap.S().Warnf("failed to save raw payload of order %s: %v", order.OrderUID, err)
+	}
+
+	applied, err := s.repo.ApplyOrderEvent(ctx, newOrder(order))
+	if err != nil {
+		return err
+	}
+	if !applied {
+		if order.EventVersion == 0 {
+			zap.S().Infof("duplicate event of order %s without event_version ignored", order.OrderUID)
+		} else {
+			zap.S().Infof("stale event of order %s ignored: event_version %d", order.OrderUID, order.EventVersion)
+		}
+		return nil
+	}
+	_, err = s.refreshCache(ctx, order.OrderUID)
+	return err
+}
+
+// GetRawOrders возвращает исходные сообщения заказа, начиная с самого нового
+func (s *Service) GetRawOrders(ctx context.Context, orderUID string) ([]models.RawOrder, error) {
+	raws, err := s.repo.GetRawOrders(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(raws) == 0 {
+		return nil, fmt.Errorf("raw payload retrieval error: %w", er.ErrOrderNotFound)
+	}
+	return raws, nil
+}
+
+// RenormalizeOrder заново разбирает последнее исходное сообщение заказа и перезаписывает
+// нормализованные данные. Используется после изменений схемы или исправления ошибок разбора.
+// Если expectedVersion не 0, данные перезаписываются только при совпадении версии заказа.
+func (s *Service) RenormalizeOrder(ctx context.Context, orderUID string, expectedVersion int64) (*models.Order, error) {
+	raws, err := s.GetRawOrders(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var order models.Order
+	if err := json.Unmarshal(raws[0].Payload, &order); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal raw payload: %v", er.ErrInvalidData, err)
+	}
+	if order.OrderUID != orderUID {
+		return nil, fmt.Errorf("%w: raw payload belongs to order %q", er.ErrInvalidData, order.OrderUID)
+	}
+	if raws[0].MajorUnits {
+		order.ToMinorUnits()
+	}
+
+	order.NormalizeStatus()
+	if expectedVersion != 0 {
+		err = s.repo.UpdateOrder(ctx, order, expectedVersion)
+	} else {
+		err = s.repo.ReplaceOrder(ctx, order)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.refreshCache(ctx, orderUID)
+}
+
+// ChangeOrderStatus переводит заказ в новый статус, если переход разрешен.
+// Если expectedVersion не 0, переход выполняется только при совпадении версии заказа.
+func (s *Service) ChangeOrderStatus(ctx context.Context, orderUID string, to models.Status, comment string, expectedVersion int64) (*models.Order, error) {
+	order, err := s.repo.GetOrder(repository.WithPrimary(ctx), orderUID)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && order.Version != expectedVersion {
+		return nil, fmt.Errorf("%w: current version is %d", er.ErrVersionConflict, order.Version)
+	}
+	if !order.Status.CanTransitionTo(to) {
+		return nil, fmt.Errorf("%w: %s -> %s", er.ErrInvalidStatusTransition, order.Status, to)
+	}
+
+	change := models.StatusChange{
+		OrderUID:  orderUID,
+		From:      order.Status,
+		To:        to,
+		Comment:   comment,
+		ChangedAt: time.Now().UTC(),
+	}
+	if err := s.repo.AppendStatusChange(ctx, change, expectedVersion); err != nil {
+		return nil, err
+	}
+	return s.refreshCache(ctx, orderUID)
+}
+
+// DeleteOrder мягко удаляет заказ и убирает его из кеша.
+// Если expectedVersion не 0, заказ удаляется только при совпадении версии.
+func (s *Service) DeleteOrder(ctx context.Context, orderUID string, expectedVersion int64) error {
+	if err := s.repo.SoftDeleteOrder(ctx, orderUID, expectedVersion); err != nil {
+		return err
+	}
+	s.cacheEvict(orderUID)
+	return nil
+}
+
+// RestoreOrder восстанавливает мягко удаленный или перенесенный в архив заказ и возвращает его в кеш
+func (s *Service) RestoreOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	err := s.repo.RestoreOrder(ctx, orderUID)
+	if errors.Is(err, er.ErrOrderNotFound) && s.archive != nil {
+		return s.RestoreArchivedOrder(ctx, orderUID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.refreshCache(ctx, orderUID)
+}
+
+// GetOrderAudit возвращает журнал аудита заказа. Журнал доступен и для безвозвратно удаленных заказов.
+func (s *Service) GetOrderAudit(ctx context.Context, orderUID string) ([]models.AuditEntry, error) {
+	entries, err := s.repo.GetOrderAudit(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("audit retrieval error: %w", er.ErrOrderNotFound)
+	}
+	return entries, nil
+}
+
+// GetStatusHistory возвращает историю статусов заказа
+func (s *Service) GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChangeResponse, error) {
+	if _, err := s.getEntry(ctx, orderUID); err != nil {
+		return nil, err
+	}
+	history, err := s.repo.GetStatusHistory(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]models.StatusChangeResponse, len(history))
+	for i, change := range history {
+		resp[i] = models.StatusChangeResponse{
+			To:        change.To.String(),
+			Comment:   change.Comment,
+			ChangedAt: change.ChangedAt,
+		}
+		if change.From != 0 {
+			resp[i].From = change.From.String()
+		}
+	}
+	return resp, nil
+}
+
+// ListOrders возвращает страницу безопасных версий заказов
+func (s *Service) ListOrders(ctx context.Context, filter models.OrderFilter, cursor string, limit int) (*models.OrderListResponse, error) {
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	page, err := s.repo.ListOrders(ctx, filter, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.OrderListResponse{
+		Items:      make([]models.OrderResponse, len(page.Orders)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Orders {
+		resp.Items[i] = *s.convertToOrderResponse(&page.Orders[i])
+	}
+	return resp, nil
+}
+
+// SearchOrdersFullText ищет заказы полнотекстово и возвращает их с подсвеченными фрагментами
+func (s *Service) SearchOrdersFullText(ctx context.Context, query string, limit int) ([]models.FullTextResult, error) {
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	hits, err := s.repo.SearchOrdersFullText(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	orderUIDs := make([]string, len(hits))
+	for i, hit := range hits {
+		orderUIDs[i] = hit.OrderUID
+	}
+	entries, err := s.getEntries(ctx, orderUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.FullTextResult, 0, len(hits))
+	for _, hit := range hits {
+		// Заказ мог быть удален между поиском и загрузкой
+		entry, ok := entries[hit.OrderUID]
+		if !ok {
+			continue
+		}
+		order, err := entry.decodeResponse()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.FullTextResult{
+			Order:   *order,
+			Rank:    hit.Rank,
+			Snippet: models.HighlightHTML(hit.Snippet),
+		})
+	}
+	return results, nil
+}
+
 // convertToOrderResponse конвертирует Order в OrderResponse
 func (s *Service) convertToOrderResponse(order *models.Order) *models.OrderResponse {
 	itemsResponse := make(models.ItemsResponse, len(order.Items))
 	for i, item := range order.Items {
 		itemsResponse[i] = models.ItemResponse{
-			TrackNumber: item.TrackNumber,
-			Price:       item.Price,
-			Name:        item.Name,
-			Sale:        item.Sale,
-			Size:        item.Size,
-			TotalPrice:  item.TotalPrice,
-			Brand:       item.Brand,
+			TrackNumber:         item.TrackNumber,
+			Price:               item.Price,
+			PriceFormatted:      order.Payment.Currency.Format(item.Price),
+			Name:                item.Name,
+			Sale:                item.Sale,
+			Size:                item.Size,
+			TotalPrice:          item.TotalPrice,
+			TotalPriceFormatted: order.Payment.Currency.Format(item.TotalPrice),
+			Brand:               item.Brand,
 		}
 	}
 
@@ -98,18 +423,24 @@ func (s *Service) convertToOrderResponse(order *models.Order) *models.OrderRespo
 		TrackNumber: order.TrackNumber,
 		Delivery:    order.Delivery,
 		Payment: models.PaymentResponse{
-			Currency:     order.Payment.Currency,
-			Provider:     order.Payment.Provider,
-			Amount:       order.Payment.Amount,
-			PaymentDt:    order.Payment.PaymentDt,
-			Bank:         order.Payment.Bank,
-			DeliveryCost: order.Payment.DeliveryCost,
-			GoodsTotal:   order.Payment.GoodsTotal,
-			CustomFee:    order.Payment.CustomFee,
+			Currency:              order.Payment.Currency,
+			Provider:              order.Payment.Provider,
+			Amount:                order.Payment.Amount,
+			AmountFormatted:       order.Payment.Currency.Format(order.Payment.Amount),
+			PaymentDt:             order.Payment.PaymentDt,
+			Bank:                  order.Payment.Bank,
+			DeliveryCost:          order.Payment.DeliveryCost,
+			DeliveryCostFormatted: order.Payment.Currency.Format(order.Payment.DeliveryCost),
+			GoodsTotal:            order.Payment.GoodsTotal,
+			GoodsTotalFormatted:   order.Payment.Currency.Format(order.Payment.GoodsTotal),
+			CustomFee:             order.Payment.CustomFee,
+			CustomFeeFormatted:    order.Payment.Currency.Format(order.Payment.CustomFee),
 		},
 		Items:           itemsResponse,
 		Locale:          order.Locale,
 		DeliveryService: order.DeliveryService,
 		DateCreated:     order.DateCreated,
+		Status:          order.Status.String(),
+		Version:         order.Version,
 	}
 }
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Формат снимка кеша:
+//
+//	magic "L0CS" | version uint16 | reconciled int64 | count uint32 |
+//	count * (uvarint длина + JSON заказа) | crc32 (IEEE) всех предыдущих байт
+//
+// reconciled - метка последней сверки кеша с БД в наносекундах Unix (0 - сверки не было).
+// Все числа фиксированной длины записываются в big endian.
+// snapshotVersion нужно увеличивать при любом изменении формата файла или JSON models.Order,
+// иначе при рестарте в кеш попадут заказы в старом формате.
+const (
+	snapshotMagic   = "L0CS"
+	snapshotVersion = 3
+)
+
+var errSnapshotCorrupted = errors.New("cache snapshot corrupted")
+
+// snapshot - содержимое файла снимка кеша
+type snapshot struct {
+	orders     [][]byte  // JSON models.Order
+	reconciled time.Time // изменения заказов до этого времени БД учтены в снимке
+}
+
+// writeSnapshot сериализует снимок в w
+func writeSnapshot(w io.Writer, snap snapshot) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+
+	var reconciled int64
+	if !snap.reconciled.IsZero() {
+		reconciled = snap.reconciled.UnixNano()
+	}
+	header := make([]byte, 0, len(snapshotMagic)+2+8+4)
+	header = append(header, snapshotMagic...)
+	header = binary.BigEndian.AppendUint16(header, snapshotVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(reconciled))
+	header = binary.BigEndian.AppendUint32(header, uint32(len(snap.orders)))
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	for _, order := range snap.orders {
+		n := binary.PutUvarint(lenBuf, uint64(len(order)))
+		if _, err := bw.Write(lenBuf[:n]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(order); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// readSnapshot читает снимок, проверяя версию формата и контрольную сумму
+func readSnapshot(data []byte) (snapshot, error) {
+	var snap snapshot
+
+	headerLen := len(snapshotMagic) + 2 + 8 + 4
+	if len(data) < headerLen+4 {
+		return snap, fmt.Errorf("%w: file too short", errSnapshotCorrupted)
+	}
+
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return snap, fmt.Errorf("%w: checksum mismatch", errSnapshotCorrupted)
+	}
+	if string(body[:len(snapshotMagic)]) != snapshotMagic {
+		return snap, fmt.Errorf("%w: bad magic", errSnapshotCorrupted)
+	}
+	if version := binary.BigEndian.Uint16(body[4:6]); version != snapshotVersion {
+		return snap, fmt.Errorf("unsupported cache snapshot version %d (expected %d)", version, snapshotVersion)
+	}
+	if reconciled := int64(binary.BigEndian.Uint64(body[6:14])); reconciled != 0 {
+		snap.reconciled = time.Unix(0, reconciled).UTC()
+	}
+	count := binary.BigEndian.Uint32(body[14:18])
+
+	r := bytes.NewReader(body[headerLen:])
+	snap.orders = make([][]byte, 0, count)
+	for i := uint32(0); i < count; i++ {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return snap, fmt.Errorf("%w: entry %d length: %v", errSnapshotCorrupted, i, err)
+		}
+		if size > uint64(r.Len()) {
+			return snap, fmt.Errorf("%w: entry %d truncated", errSnapshotCorrupted, i)
+		}
+		order := make([]byte, size)
+		if _, err := io.ReadFull(r, order); err != nil {
+			return snap, fmt.Errorf("%w: entry %d: %v", errSnapshotCorrupted, i, err)
+		}
+		snap.orders = append(snap.orders, order)
+	}
+	if r.Len() != 0 {
+		return snap, fmt.Errorf("%w: trailing data", errSnapshotCorrupted)
+	}
+
+	return snap, nil
+}
+
+// SaveSnapshot атомарно записывает текущее содержимое кеша в файл снимка
+func (s *Service) SaveSnapshot() error {
+	if s.cfg.SnapshotPath == "" {
+		return nil
+	}
+
+	var snap snapshot
+	s.mu.RLock()
+	snap.reconciled = s.reconciledAt
+	snap.orders = make([][]byte, 0, len(s.cache))
+	for _, entry := range s.cache {
+		snap.orders = append(snap.orders, entry.order)
+	}
+	s.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.cfg.SnapshotPath), filepath.Base(s.cfg.SnapshotPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeSnapshot(tmp, snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.cfg.SnapshotPath); err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot читает файл снимка и возвращает записи кеша по order_uid и метку сверки
+func (s *Service) LoadSnapshot() (map[string]cacheEntry, time.Time, error) {
+	data, err := os.ReadFile(s.cfg.SnapshotPath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	snap, err := readSnapshot(data)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	entries := make(map[string]cacheEntry, len(snap.orders))
+	for _, orderJSON := range snap.orders {
+		// Ответ рендерится заново, чтобы снимок не зависел от формата OrderResponse
+		order, err := cacheEntry{order: orderJSON}.decodeOrder()
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("%w: %v", errSnapshotCorrupted, err)
+		}
+		entry, err := s.newCacheEntry(order)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		entries[order.OrderUID] = entry
+	}
+	return entries, snap.reconciled, nil
+}
+
+// restoreFromSnapshot загружает снимок в кеш и догружает из основной БД заказы,
+// изменившиеся после сохраненной в снимке метки сверки
+func (s *Service) restoreFromSnapshot(ctx context.Context) error {
+	entries, reconciled, err := s.LoadSnapshot()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for orderUID, entry := range entries {
+		s.cache[orderUID] = entry
+	}
+	s.reconciledAt = reconciled
+	s.mu.Unlock()
+
+	return s.ReconcileCache(ctx)
+}
+
+// RunSnapshots периодически сохраняет снимок кеша до отмены контекста
+func (s *Service) RunSnapshots(ctx context.Context) {
+	if s.cfg.SnapshotPath == "" || s.cfg.SnapshotInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.SaveSnapshot(); err != nil {
+				zap.S().Errorf("failed to save cache snapshot: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.SaveSnapshot(); err != nil {
+				zap.S().Errorf("failed to save cache snapshot: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"l0/internal/models"
+)
+
+// Tokens - токены API по имени пользователя. Из переменной окружения читаются в формате
+// user1:token1,user2:token2: env v6 не разбирает map-поля сам.
+type Tokens map[string]string
+
+// UnmarshalText разбирает токены в формате user1:token1,user2:token2
+func (t *Tokens) UnmarshalText(text []byte) error {
+	tokens := make(Tokens)
+	for i, pair := range strings.Split(string(text), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		// Сам токен в ошибку не попадает
+		user, token, ok := strings.Cut(pair, ":")
+		if !ok || user == "" || token == "" {
+			return fmt.Errorf("invalid token entry %d: expected user:token", i+1)
+		}
+		tokens[user] = token
+	}
+	*t = tokens
+	return nil
+}
+
+type adminUserKey struct{}
+
+type ingestClientKey struct{}
+
+// adminUserFromContext возвращает имя администратора, прошедшего аутентификацию
+func adminUserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(adminUserKey{}).(string)
+	return user
+}
+
+// ingestClientFromContext возвращает имя партнера, прошедшего аутентификацию в API приема заказов
+func ingestClientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(ingestClientKey{}).(string)
+	return client
+}
+
+// bearerToken извлекает токен из заголовка Authorization: Bearer <token>
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// lookupToken ищет пользователя по токену за постоянное время
+func lookupToken(tokens map[string]string, token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	var found string
+	for user, userToken := range tokens {
+		if subtle.ConstantTimeCompare([]byte(userToken), []byte(token)) == 1 {
+			found = user
+		}
+	}
+	return found, found != ""
+}
+
+// adminAuthMiddleware пропускает только запросы с токеном из ADMIN_TOKENS
+// и кладет имя администратора в контекст запроса, в том числе как источник изменений для журнала аудита
+func adminAuthMiddleware(tokens map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := lookupToken(tokens, bearerToken(r))
+			if !ok {
+				writeJSONResponse(w, http.StatusUnauthorized, Response{
+					Status: "error",
+					Msg:    "unauthorized",
+				})
+				return
+			}
+			ctx := context.WithValue(r.Context(), adminUserKey{}, user)
+			ctx = models.WithAuditSource(ctx, models.AuditSource{Kind: models.AuditSourceAdmin, Actor: user})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ingestAuthMiddleware пропускает только запросы с токеном из INGEST_TOKENS
+// и кладет имя партнера в контекст запроса, в том числе как источник изменений для журнала аудита
+func ingestAuthMiddleware(tokens map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, ok := lookupToken(tokens, bearerToken(r))
+			if !ok {
+				writeJSONResponse(w, http.StatusUnauthorized, Response{
+					Status: "error",
+					Msg:    "unauthorized",
+				})
+				return
+			}
+			ctx := context.WithValue(r.Context(), ingestClientKey{}, client)
+			ctx = models.WithAuditSource(ctx, models.AuditSource{Kind: models.AuditSourceAPI, Actor: client})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
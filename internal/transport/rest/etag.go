@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"l0/pkg/er"
+)
+
+// etag возвращает ETag заказа, построенный по его версии
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// setETag выставляет ETag заказа. У заказов без версии (архивных, сохраненных до появления версий)
+// ETag нет: версия 0 в If-Match означает "без проверки" и не может быть условием изменения.
+func setETag(w http.ResponseWriter, version int64) {
+	if version > 0 {
+		w.Header().Set("ETag", etag(version))
+	}
+}
+
+// parseIfMatch возвращает ожидаемую версию заказа из заголовка If-Match.
+// 0 означает, что заголовок не задан или равен "*" и версия не проверяется.
+func parseIfMatch(r *http.Request) (int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	if len(v) < 3 || v[0] != '"' || v[len(v)-1] != '"' {
+		return 0, fmt.Errorf("%w: If-Match must be a single order ETag", er.ErrInvalidData)
+	}
+	version, err := strconv.ParseInt(v[1:len(v)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("%w: If-Match must be a single order ETag", er.ErrInvalidData)
+	}
+	return version, nil
+}
+
+// notModified проверяет, совпадает ли один из ETag в If-None-Match с текущей версией заказа
+func notModified(r *http.Request, version int64) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || version <= 0 {
+		return false
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
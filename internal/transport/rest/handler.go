@@ -1,10 +1,19 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"l0/internal/models"
 	"l0/internal/service"
 	"l0/pkg/er"
 
@@ -12,8 +21,12 @@ import (
 	"go.uber.org/zap"
 )
 
+// healthTimeout ограничивает проверку здоровья, чтобы зависшая БД не держала запрос
+const healthTimeout = 3 * time.Second
+
 type Handler struct {
-	svc *service.Service
+	svc       *service.Service
+	forwarder OrderForwarder // nil - заказы из HTTP API сохраняются напрямую
 }
 
 func NewHandler(svc *service.Service) *Handler {
@@ -32,6 +45,63 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, resp Response) {
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// writeRawDataResponse пишет успешный ответ с уже сериализованными данными без повторного кодирования
+func writeRawDataResponse(w http.ResponseWriter, statusCode int, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(`{"status":"ok","data":`))
+	_, _ = w.Write(data)
+	_, _ = w.Write([]byte("}\n"))
+}
+
+// writeErrorResponse отображает ошибку сервиса на HTTP статус
+func writeErrorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, er.ErrOrderNotFound):
+		zap.S().Infof("request failed: %v", err)
+		writeJSONResponse(w, http.StatusNotFound, Response{
+			Status: "error",
+			Msg:    "order not found",
+		})
+	case errors.Is(err, er.ErrOrderExists), errors.Is(err, er.ErrIdempotencyKeyInProgress):
+		zap.S().Infof("request failed: %v", err)
+		writeJSONResponse(w, http.StatusConflict, Response{
+			Status: "error",
+			Msg:    err.Error(),
+		})
+	case errors.Is(err, er.ErrVersionConflict):
+		zap.S().Infof("request failed: %v", err)
+		writeJSONResponse(w, http.StatusPreconditionFailed, Response{
+			Status: "error",
+			Msg:    err.Error(),
+		})
+	case errors.Is(err, er.ErrInvalidStatusTransition):
+		zap.S().Infof("request failed: %v", err)
+		writeJSONResponse(w, http.StatusConflict, Response{
+			Status: "error",
+			Msg:    err.Error(),
+		})
+	case errors.Is(err, er.ErrRateNotFound), errors.Is(err, er.ErrIdempotencyKeyReused):
+		zap.S().Infof("request failed: %v", err)
+		writeJSONResponse(w, http.StatusUnprocessableEntity, Response{
+			Status: "error",
+			Msg:    err.Error(),
+		})
+	case errors.Is(err, er.ErrInvalidData):
+		zap.S().Infof("request failed: %v", err)
+		writeJSONResponse(w, http.StatusBadRequest, Response{
+			Status: "error",
+			Msg:    err.Error(),
+		})
+	default:
+		zap.S().Errorf("request failed: %v", err)
+		writeJSONResponse(w, http.StatusInternalServerError, Response{
+			Status: "error",
+			Msg:    "internal server error",
+		})
+	}
+}
+
 func (h *Handler) GetOrder() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -43,29 +113,576 @@ func (h *Handler) GetOrder() http.HandlerFunc {
 			})
 			return
 		}
-		order, err := h.svc.GetOrderResponse(r.Context(), orderUID)
+
+		// С ?currency= ответ зависит от курсов, поэтому собирается заново и отдается без ETag
+		if currencies := r.URL.Query().Get("currency"); currencies != "" {
+			h.writeConvertedOrder(w, r, orderUID, currencies)
+			return
+		}
+
+		order, version, err := h.svc.GetOrderResponseJSON(r.Context(), orderUID)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		setETag(w, version)
+		if notModified(r, version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeRawDataResponse(w, http.StatusOK, order)
+	}
+}
+
+// writeConvertedOrder пишет заказ с суммой платежа в валютах из списка через запятую
+func (h *Handler) writeConvertedOrder(w http.ResponseWriter, r *http.Request, orderUID, list string) {
+	var currencies []models.Currency
+	for _, code := range strings.Split(list, ",") {
+		currency := models.Currency(strings.ToUpper(strings.TrimSpace(code)))
+		if !currency.Valid() {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    fmt.Sprintf("unknown currency %q, expected ISO 4217 code", code),
+			})
+			return
+		}
+		currencies = append(currencies, currency)
+	}
+
+	order, err := h.svc.GetOrderResponseConverted(r.Context(), orderUID, currencies)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, Response{
+		Status: "ok",
+		Data:   order,
+	})
+}
+
+// writeOrderResponse пишет безопасное представление заказа с ETag его текущей версии
+func (h *Handler) writeOrderResponse(w http.ResponseWriter, r *http.Request, orderUID string) {
+	order, version, err := h.svc.GetOrderResponseJSON(r.Context(), orderUID)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+	setETag(w, version)
+	writeRawDataResponse(w, http.StatusOK, order)
+}
+
+// GetStatusHistory возвращает историю статусов заказа: GET /order/{order_uid}/history
+func (h *Handler) GetStatusHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		history, err := h.svc.GetStatusHistory(r.Context(), orderUID)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+			Data:   history,
+		})
+	}
+}
+
+// statusChangeRequest - тело запроса смены статуса
+type statusChangeRequest struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+}
+
+// ChangeOrderStatus переводит заказ в новый статус: POST /admin/orders/{order_uid}/status.
+// С заголовком If-Match статус меняется, только если версия заказа не изменилась.
+func (h *Handler) ChangeOrderStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		expectedVersion, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+
+		var req statusChangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    "invalid request body",
+			})
+			return
+		}
+		status, err := models.ParseStatus(req.Status)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+
+		if _, err := h.svc.ChangeOrderStatus(r.Context(), orderUID, status, req.Comment, expectedVersion); err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		zap.S().Infof("order %s moved to %s by %s", orderUID, status, adminUserFromContext(r.Context()))
+
+		h.writeOrderResponse(w, r, orderUID)
+	}
+}
+
+// maxPatchBodySize ограничивает размер тела PATCH /order/{order_uid}
+const maxPatchBodySize = 1 << 20
+
+// PatchOrder исправляет доставку, товары или статус заказа JSON merge patch'ем:
+// PATCH /order/{order_uid}. С заголовком If-Match заказ меняется, только если версия не изменилась.
+func (h *Handler) PatchOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+			writeJSONResponse(w, http.StatusUnsupportedMediaType, Response{
+				Status: "error",
+				Msg:    "content type must be application/merge-patch+json",
+			})
+			return
+		}
+		expectedVersion, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+
+		patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBodySize))
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    "invalid request body",
+			})
+			return
+		}
+
+		if _, err := h.svc.PatchOrder(r.Context(), orderUID, patch, expectedVersion); err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		zap.S().Infof("order %s patched by %s", orderUID, adminUserFromContext(r.Context()))
+
+		h.writeOrderResponse(w, r, orderUID)
+	}
+}
+
+// cancelRequest - тело запроса отмены заказа; тело можно не передавать
+type cancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CancelOrder отменяет заказ: POST /order/{order_uid}/cancel.
+// Заказ остается доступным со статусом cancelled, повторная отмена ничего не меняет.
+// С заголовком If-Match заказ отменяется, только если версия не изменилась.
+func (h *Handler) CancelOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		expectedVersion, err := parseIfMatch(r)
 		if err != nil {
-			if errors.Is(err, er.ErrOrderNotFound) {
-				zap.S().Infof("failed to get order: %v", err)
-				writeJSONResponse(w, http.StatusNotFound, Response{
-					Status: "error",
-					Msg:    "order not found",
-					Data:   nil,
-				})
-				return
-			}
-			zap.S().Infof("failed to get order: %v", err)
-			writeJSONResponse(w, http.StatusInternalServerError, Response{
+			writeErrorResponse(w, err)
+			return
+		}
+
+		var req cancelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
 				Status: "error",
-				Msg:    "internal server error",
-				Data:   nil,
+				Msg:    "invalid request body",
 			})
 			return
 		}
+
+		if _, err := h.svc.CancelOrder(r.Context(), orderUID, req.Reason, expectedVersion); err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		zap.S().Infof("order %s cancelled by %s", orderUID, adminUserFromContext(r.Context()))
+
+		h.writeOrderResponse(w, r, orderUID)
+	}
+}
+
+// GetRawOrders возвращает исходные сообщения заказа: GET /admin/orders/{order_uid}/raw
+func (h *Handler) GetRawOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		raws, err := h.svc.GetRawOrders(r.Context(), orderUID)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+			Data:   raws,
+		})
+	}
+}
+
+// GetOrderAudit возвращает журнал аудита заказа: GET /admin/orders/{order_uid}/audit
+func (h *Handler) GetOrderAudit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		entries, err := h.svc.GetOrderAudit(r.Context(), orderUID)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+			Data:   entries,
+		})
+	}
+}
+
+// maxRatesUploadSize ограничивает размер загружаемого файла курсов
+const maxRatesUploadSize = 10 << 20
+
+// UploadExchangeRates загружает курсы валют из CSV date,currency,rate: PUT /admin/rates
+func (h *Handler) UploadExchangeRates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rates, err := models.ParseExchangeRatesCSV(http.MaxBytesReader(w, r.Body, maxRatesUploadSize))
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+
+		saved, err := h.svc.ImportExchangeRates(r.Context(), rates)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		zap.S().Infof("%d exchange rates uploaded by %s", saved, adminUserFromContext(r.Context()))
+
 		writeJSONResponse(w, http.StatusOK, Response{
 			Status: "ok",
-			Msg:    "",
-			Data:   order,
+			Data:   map[string]int{"saved": saved},
 		})
 	}
 }
+
+// RenormalizeOrder пересобирает заказ из последнего исходного сообщения:
+// POST /admin/orders/{order_uid}/renormalize. Учитывает заголовок If-Match.
+func (h *Handler) RenormalizeOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		expectedVersion, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		if _, err := h.svc.RenormalizeOrder(r.Context(), orderUID, expectedVersion); err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		zap.S().Infof("order %s renormalized by %s", orderUID, adminUserFromContext(r.Context()))
+
+		h.writeOrderResponse(w, r, orderUID)
+	}
+}
+
+// DeleteOrder мягко удаляет заказ: DELETE /admin/orders/{order_uid}. Учитывает заголовок If-Match.
+func (h *Handler) DeleteOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		expectedVersion, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		if err := h.svc.DeleteOrder(r.Context(), orderUID, expectedVersion); err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		zap.S().Infof("order %s deleted by %s", orderUID, adminUserFromContext(r.Context()))
+
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+		})
+	}
+}
+
+// RestoreOrder восстанавливает мягко удаленный заказ: POST /admin/orders/{order_uid}/restore
+func (h *Handler) RestoreOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := mux.Vars(r)["order_uid"]
+		if _, err := h.svc.RestoreOrder(r.Context(), orderUID); err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		zap.S().Infof("order %s restored by %s", orderUID, adminUserFromContext(r.Context()))
+
+		h.writeOrderResponse(w, r, orderUID)
+	}
+}
+
+// Health сообщает состояние сервиса и пулов соединений БД: GET /health.
+// Если основная БД недоступна, возвращает 503.
+func (h *Handler) Health() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+		defer cancel()
+
+		health := h.svc.Health(ctx)
+		if !health.Healthy() {
+			writeJSONResponse(w, http.StatusServiceUnavailable, Response{
+				Status: "error",
+				Msg:    "database is unavailable",
+				Data:   health,
+			})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+			Data:   health,
+		})
+	}
+}
+
+// ListOrders возвращает страницу заказов: GET /orders?limit=&cursor=
+func (h *Handler) ListOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.writeOrderPage(w, r, models.OrderFilter{})
+	}
+}
+
+// SearchOrders ищет заказы по фильтрам: GET /orders/search?track_number=&customer_id=&...
+func (h *Handler) SearchOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseOrderFilter(r.URL.Query())
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    err.Error(),
+			})
+			return
+		}
+		if filter.IsEmpty() {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    "at least one search filter is required",
+			})
+			return
+		}
+		h.writeOrderPage(w, r, filter)
+	}
+}
+
+// SearchOrdersFullText выполняет полнотекстовый поиск: GET /orders/fulltext?q=&limit=
+func (h *Handler) SearchOrdersFullText() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, err := parseIntParam(query, "limit")
+		if err != nil || limit < 0 {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    "limit must be a positive integer",
+			})
+			return
+		}
+
+		results, err := h.svc.SearchOrdersFullText(r.Context(), query.Get("q"), limit)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+			Data:   results,
+		})
+	}
+}
+
+// maxBatchGetBodySize ограничивает размер тела пакетного запроса заказов
+const maxBatchGetBodySize = 1 << 20
+
+// batchGetRequest - тело пакетного запроса заказов
+type batchGetRequest struct {
+	OrderUIDs []string `json:"order_uids"`
+}
+
+// BatchGetOrders возвращает несколько заказов за один запрос: POST /orders:batchGet.
+// Результаты идут в порядке order_uids, ненайденные заказы помечаются статусом not_found.
+func (h *Handler) BatchGetOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req batchGetRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchGetBodySize)).Decode(&req); err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    "invalid request body",
+			})
+			return
+		}
+
+		results, err := h.svc.BatchGetOrders(r.Context(), req.OrderUIDs)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+			Data:   results,
+		})
+	}
+}
+
+// GetCustomerOrders возвращает заказы покупателя и сводку по ним:
+// GET /customers/{customer_id}/orders?limit=&cursor=
+func (h *Handler) GetCustomerOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, err := parseIntParam(query, "limit")
+		if err != nil || limit < 0 {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    "limit must be a positive integer",
+			})
+			return
+		}
+
+		resp, err := h.svc.GetCustomerOrders(r.Context(), mux.Vars(r)["customer_id"], query.Get("cursor"), limit)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+			Data:   resp,
+		})
+	}
+}
+
+// SalesAnalytics возвращает временные ряды продаж:
+// GET /analytics/{dimension}?interval=day|week&from=&to=, dimension - total, brand,
+// delivery_service, provider или region. to в формате даты включает весь день.
+func (h *Handler) SalesAnalytics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		sales := models.SalesQuery{
+			Dimension: models.SalesDimension(mux.Vars(r)["dimension"]),
+			Interval:  models.SalesInterval(query.Get("interval")),
+		}
+		if sales.Interval == "" {
+			sales.Interval = models.SalesDaily
+		}
+
+		var err error
+		var dateOnly bool
+		if sales.From, _, err = parseDateParam(query, "from"); err == nil {
+			sales.To, dateOnly, err = parseDateParam(query, "to")
+		}
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    err.Error(),
+			})
+			return
+		}
+		if dateOnly {
+			sales.To = sales.To.AddDate(0, 0, 1)
+		}
+
+		report, err := h.svc.SalesReport(r.Context(), sales)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, Response{
+			Status: "ok",
+			Data:   report,
+		})
+	}
+}
+
+// writeOrderPage отдает страницу заказов по фильтру с учетом параметров limit и cursor
+func (h *Handler) writeOrderPage(w http.ResponseWriter, r *http.Request, filter models.OrderFilter) {
+	query := r.URL.Query()
+
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    "limit must be a positive integer",
+			})
+			return
+		}
+		limit = n
+	}
+
+	page, err := h.svc.ListOrders(r.Context(), filter, query.Get("cursor"), limit)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, Response{
+		Status: "ok",
+		Data:   page,
+	})
+}
+
+// parseOrderFilter разбирает параметры поиска заказов.
+// Даты принимаются в формате RFC 3339 или YYYY-MM-DD; date_to в формате даты включает весь день.
+func parseOrderFilter(query url.Values) (models.OrderFilter, error) {
+	filter := models.OrderFilter{
+		TrackNumber:     query.Get("track_number"),
+		CustomerID:      query.Get("customer_id"),
+		Phone:           query.Get("phone"),
+		Email:           query.Get("email"),
+		DeliveryService: query.Get("delivery_service"),
+		PaymentProvider: query.Get("provider"),
+		Bank:            query.Get("bank"),
+		Brand:           query.Get("brand"),
+	}
+
+	var err error
+	if filter.NmID, err = parseIntParam(query, "nm_id"); err != nil {
+		return filter, err
+	}
+	if filter.ChrtID, err = parseIntParam(query, "chrt_id"); err != nil {
+		return filter, err
+	}
+	if filter.DateFrom, _, err = parseDateParam(query, "date_from"); err != nil {
+		return filter, err
+	}
+	var dateOnly bool
+	if filter.DateTo, dateOnly, err = parseDateParam(query, "date_to"); err != nil {
+		return filter, err
+	}
+	if dateOnly {
+		filter.DateTo = filter.DateTo.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
+
+// parseIntParam разбирает необязательный целочисленный параметр
+func parseIntParam(query url.Values, name string) (int, error) {
+	v := query.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+// parseDateParam разбирает необязательную дату, dateOnly сообщает, что время не было указано
+func parseDateParam(query url.Values, name string) (t time.Time, dateOnly bool, err error) {
+	v := query.Get(name)
+	if v == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err = time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	if t, err = time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%s must be a date (YYYY-MM-DD) or RFC 3339 timestamp", name)
+}
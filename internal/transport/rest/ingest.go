@@ -0,0 +1,250 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"l0/internal/models"
+	"l0/pkg/er"
+	"mime"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Ограничения API приема заказов
+const (
+	maxIngestBodySize  = 10 << 20
+	maxIngestBatchSize = 1000
+)
+
+// OrderForwarder отправляет заказ в брокер вместо записи в БД (реализуется broker.Producer)
+type OrderForwarder interface {
+	SendOrder(ctx context.Context, order *models.Order) error
+}
+
+// WithForwarder включает пересылку заказов из POST /orders в брокер
+func (h *Handler) WithForwarder(forwarder OrderForwarder) *Handler {
+	h.forwarder = forwarder
+	return h
+}
+
+// ingestResult - результат приема одного заказа
+type ingestResult struct {
+	OrderUID string `json:"order_uid,omitempty"`
+	Status   int    `json:"status"` // HTTP статус для этого заказа
+	Msg      string `json:"msg,omitempty"`
+}
+
+// IngestOrders принимает заказы от партнеров, которые не могут писать в Kafka: POST /orders.
+// Тело - один заказ или массив заказов в JSON либо NDJSON (Content-Type: application/x-ndjson).
+// С заголовком Idempotency-Key повтор того же запроса возвращает сохраненный ответ.
+func (h *Handler) IngestOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJSONResponse(w, http.StatusRequestEntityTooLarge, Response{
+					Status: "error",
+					Msg:    fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit),
+				})
+				return
+			}
+			writeJSONResponse(w, http.StatusBadRequest, Response{
+				Status: "error",
+				Msg:    "invalid request body",
+			})
+			return
+		}
+
+		ctx := r.Context()
+		client := ingestClientFromContext(ctx)
+		key := r.Header.Get("Idempotency-Key")
+		if key != "" {
+			stored, err := h.svc.ReserveIdempotencyKey(ctx, client, key, body)
+			if err != nil {
+				writeErrorResponse(w, err)
+				return
+			}
+			if stored != nil {
+				w.Header().Set("Idempotent-Replayed", "true")
+				writeStoredResponse(w, stored.StatusCode, stored.Response)
+				return
+			}
+		}
+
+		statusCode, resp, retryable := h.ingest(ctx, r.Header.Get("Content-Type"), body)
+		payload, err := json.Marshal(resp)
+		if err != nil {
+			zap.S().Errorf("failed to marshal ingest response: %v", err)
+			statusCode, payload = http.StatusInternalServerError, []byte(`{"status":"error","msg":"internal server error"}`)
+		}
+		payload = append(payload, '\n')
+
+		if key != "" {
+			// Ответ сохраняется и после отмены запроса клиентом, иначе повтор попадет на занятый ключ
+			saveCtx := context.WithoutCancel(ctx)
+			if retryable {
+				err = h.svc.ReleaseIdempotencyKey(saveCtx, client, key)
+			} else {
+				err = h.svc.CompleteIdempotencyKey(saveCtx, client, key, statusCode, payload)
+			}
+			if err != nil {
+				zap.S().Errorf("failed to save idempotency key %q of %s: %v", key, client, err)
+			}
+		}
+		writeStoredResponse(w, statusCode, payload)
+	}
+}
+
+// ingest разбирает тело и принимает каждый заказ. Для пакета статус ответа общий для всех заказов,
+// если он у них совпадает, иначе 207 Multi-Status; статус каждого заказа - в его результате.
+// retryable сообщает, что ни один заказ не принят и хотя бы один - из-за внутренней ошибки, поэтому запрос
+// можно повторить целиком. Если часть заказов принята, ответ с результатами сохраняется как есть:
+// повтор с тем же ключом вернет его, а не 409 для уже принятых заказов.
+func (h *Handler) ingest(ctx context.Context, contentType string, body []byte) (statusCode int, resp Response, retryable bool) {
+	docs, batch, err := splitOrders(contentType, body)
+	if err != nil {
+		return http.StatusBadRequest, Response{Status: "error", Msg: err.Error()}, false
+	}
+	if len(docs) == 0 {
+		return http.StatusBadRequest, Response{Status: "error", Msg: "no orders in request body"}, false
+	}
+	if len(docs) > maxIngestBatchSize {
+		return http.StatusRequestEntityTooLarge, Response{
+			Status: "error",
+			Msg:    fmt.Sprintf("at most %d orders per request", maxIngestBatchSize),
+		}, false
+	}
+
+	results := make([]ingestResult, len(docs))
+	var accepted bool
+	for i, doc := range docs {
+		results[i] = h.ingestOrder(ctx, doc)
+		switch {
+		case results[i].Status >= http.StatusInternalServerError:
+			retryable = true
+		case results[i].Status < http.StatusBadRequest:
+			accepted = true
+		}
+	}
+	retryable = retryable && !accepted
+
+	if !batch {
+		result := results[0]
+		if result.Status >= http.StatusBadRequest {
+			return result.Status, Response{Status: "error", Msg: result.Msg}, retryable
+		}
+		return result.Status, Response{Status: "ok", Data: result}, false
+	}
+
+	statusCode, status := results[0].Status, "ok"
+	for _, result := range results {
+		if result.Status != statusCode {
+			statusCode = http.StatusMultiStatus
+		}
+		if result.Status >= http.StatusBadRequest {
+			status = "error"
+		}
+	}
+	return statusCode, Response{Status: status, Data: results}, retryable
+}
+
+// ingestOrder принимает один заказ: сохраняет его тем же путем, что и consumer Kafka,
+// или пересылает в брокер, если он настроен. Существующий заказ в обоих случаях отклоняется с 409.
+func (h *Handler) ingestOrder(ctx context.Context, doc json.RawMessage) ingestResult {
+	var order models.Order
+	if err := json.Unmarshal(doc, &order); err != nil {
+		return ingestResult{Status: http.StatusUnprocessableEntity, Msg: fmt.Sprintf("%v: %v", er.ErrInvalidData, err)}
+	}
+	result := ingestResult{OrderUID: order.OrderUID}
+
+	if h.forwarder != nil {
+		if err := h.svc.ReserveNewOrder(ctx, &order); err != nil {
+			return ingestError(result, err)
+		}
+		if err := h.forwarder.SendOrder(ctx, &order); err != nil {
+			zap.S().Errorf("failed to forward order %s: %v", order.OrderUID, err)
+			if err := h.svc.ReleaseNewOrder(ctx, order.OrderUID); err != nil {
+				zap.S().Errorf("failed to release order %s: %v", order.OrderUID, err)
+			}
+			result.Status, result.Msg = http.StatusServiceUnavailable, "failed to forward order"
+			return result
+		}
+		result.Status = http.StatusAccepted
+		return result
+	}
+
+	raw := models.RawOrder{Payload: doc, Topic: models.RawTopicHTTP, IngestedAt: time.Now()}
+	if err := h.svc.SubmitOrder(ctx, &order, raw); err != nil {
+		return ingestError(result, err)
+	}
+	result.Status = http.StatusCreated
+	return result
+}
+
+// ingestError отображает ошибку сохранения заказа на статус результата
+func ingestError(result ingestResult, err error) ingestResult {
+	switch {
+	case errors.Is(err, er.ErrOrderExists):
+		result.Status, result.Msg = http.StatusConflict, err.Error()
+	case errors.Is(err, er.ErrInvalidData):
+		result.Status, result.Msg = http.StatusUnprocessableEntity, err.Error()
+	default:
+		zap.S().Errorf("failed to ingest order %s: %v", result.OrderUID, err)
+		result.Status, result.Msg = http.StatusInternalServerError, "internal server error"
+	}
+	return result
+}
+
+// splitOrders делит тело запроса на документы заказов. batch сообщает, что тело - массив или NDJSON.
+func splitOrders(contentType string, body []byte) (docs []json.RawMessage, batch bool, err error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-ndjson" || mediaType == "application/ndjson" {
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(make([]byte, 0, 64<<10), maxIngestBodySize)
+		for line := 1; scanner.Scan(); line++ {
+			doc := bytes.TrimSpace(scanner.Bytes())
+			if len(doc) == 0 {
+				continue
+			}
+			if !json.Valid(doc) {
+				return nil, true, fmt.Errorf("line %d is not valid JSON", line)
+			}
+			docs = append(docs, append(json.RawMessage(nil), doc...))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, true, fmt.Errorf("invalid NDJSON body: %v", err)
+		}
+		return docs, true, nil
+	}
+
+	body = bytes.TrimSpace(body)
+	switch {
+	case len(body) > 0 && body[0] == '[':
+		if err := json.Unmarshal(body, &docs); err != nil {
+			return nil, true, fmt.Errorf("invalid JSON array: %v", err)
+		}
+		return docs, true, nil
+	case len(body) > 0 && body[0] == '{':
+		if !json.Valid(body) {
+			return nil, false, errors.New("invalid JSON body")
+		}
+		return []json.RawMessage{body}, false, nil
+	default:
+		return nil, false, errors.New("request body must be a JSON order, an array of orders or NDJSON")
+	}
+}
+
+// writeStoredResponse пишет уже сериализованный JSON ответ
+func writeStoredResponse(w http.ResponseWriter, statusCode int, payload []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(payload)
+}
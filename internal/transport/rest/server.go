@@ -9,14 +9,21 @@ import (
 
 type Config struct {
 	Port string `env:"SERVER_PORT"`
+	// AdminTokens - токены административного API в формате user1:token1,user2:token2
+	AdminTokens Tokens `env:"ADMIN_TOKENS"`
+	// IngestTokens - токены партнеров для POST /orders в том же формате
+	IngestTokens Tokens `env:"INGEST_TOKENS"`
+	// IngestForwardToKafka - отправлять заказы из POST /orders в Kafka вместо записи в БД
+	IngestForwardToKafka bool `env:"INGEST_FORWARD_TO_KAFKA"`
 }
 
 // CORS middleware для разрешения кросс-доменных запросов
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, Idempotency-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -33,7 +40,32 @@ func CreateServer(cfg Config, handler *Handler) *http.Server {
 	r.Use(corsMiddleware)
 
 	// API маршруты
+	r.HandleFunc("/health", handler.Health()).Methods("GET")
 	r.HandleFunc("/order/{order_uid}", handler.GetOrder()).Methods("GET")
+	// Исправление и отмена заказа доступны по токенам административного API
+	r.Handle("/order/{order_uid}", adminAuthMiddleware(cfg.AdminTokens)(handler.PatchOrder())).Methods("PATCH")
+	r.Handle("/order/{order_uid}/cancel", adminAuthMiddleware(cfg.AdminTokens)(handler.CancelOrder())).Methods("POST")
+	r.HandleFunc("/order/{order_uid}/history", handler.GetStatusHistory()).Methods("GET")
+	r.Handle("/orders", ingestAuthMiddleware(cfg.IngestTokens)(handler.IngestOrders())).Methods("POST")
+	// Списки и поиск отдают доставку многих заказов сразу, а поиск находит их по телефону, email
+	// и ID покупателя, поэтому они, как и заказы покупателя, доступны только по токенам административного API
+	r.Handle("/orders", adminAuthMiddleware(cfg.AdminTokens)(handler.ListOrders())).Methods("GET")
+	r.Handle("/orders:batchGet", adminAuthMiddleware(cfg.AdminTokens)(handler.BatchGetOrders())).Methods("POST")
+	r.Handle("/orders/search", adminAuthMiddleware(cfg.AdminTokens)(handler.SearchOrders())).Methods("GET")
+	r.Handle("/orders/fulltext", adminAuthMiddleware(cfg.AdminTokens)(handler.SearchOrdersFullText())).Methods("GET")
+	r.Handle("/customers/{customer_id}/orders", adminAuthMiddleware(cfg.AdminTokens)(handler.GetCustomerOrders())).Methods("GET")
+	r.HandleFunc("/analytics/{dimension}", handler.SalesAnalytics()).Methods("GET")
+
+	// Административные маршруты
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(adminAuthMiddleware(cfg.AdminTokens))
+	admin.HandleFunc("/orders/{order_uid}/raw", handler.GetRawOrders()).Methods("GET")
+	admin.HandleFunc("/orders/{order_uid}/audit", handler.GetOrderAudit()).Methods("GET")
+	admin.HandleFunc("/orders/{order_uid}/renormalize", handler.RenormalizeOrder()).Methods("POST")
+	admin.HandleFunc("/orders/{order_uid}/status", handler.ChangeOrderStatus()).Methods("POST")
+	admin.HandleFunc("/orders/{order_uid}", handler.DeleteOrder()).Methods("DELETE")
+	admin.HandleFunc("/orders/{order_uid}/restore", handler.RestoreOrder()).Methods("POST")
+	admin.HandleFunc("/rates", handler.UploadExchangeRates()).Methods("PUT")
 
 	// Статические файлы для веб-интерфейса
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("web")))
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"l0/internal/repository"
+	"l0/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOrderListsRequireAdminToken(t *testing.T) {
+	repo, err := repository.NewRepository(repository.Config{Driver: "memory"})
+	if err != nil {
+		t.Fatalf("create repository: %v", err)
+	}
+	defer repo.Close()
+	svc, err := service.NewService(service.Config{}, repo)
+	if err != nil {
+		t.Fatalf("create service: %v", err)
+	}
+	server := CreateServer(Config{AdminTokens: map[string]string{"alice": "secret-token"}}, NewHandler(svc))
+
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"GET", "/orders", ""},
+		{"GET", "/orders/search?customer_id=c1", ""},
+		{"GET", "/orders/search?phone=%2B9720000000", ""},
+		{"GET", "/orders/search?email=test%40gmail.com", ""},
+		{"GET", "/orders/fulltext?q=Testov", ""},
+		{"POST", "/orders:batchGet", `{"order_uids": ["a"]}`},
+		{"GET", "/customers/c1/orders", ""},
+	}
+	tokens := []struct {
+		name     string
+		header   string
+		wantAuth bool
+	}{
+		{"without token", "", false},
+		{"with wrong token", "Bearer wrong-token", false},
+		{"with admin token", "Bearer secret-token", true},
+	}
+	for _, route := range routes {
+		for _, tt := range tokens {
+			t.Run(route.method+" "+route.path+" "+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+				if tt.header != "" {
+					req.Header.Set("Authorization", tt.header)
+				}
+				rec := httptest.NewRecorder()
+				server.Handler.ServeHTTP(rec, req)
+
+				if authorized := rec.Code != http.StatusUnauthorized; authorized != tt.wantAuth {
+					t.Fatalf("status %d, want authorized %t: %s", rec.Code, tt.wantAuth, rec.Body)
+				}
+			})
+		}
+	}
+}
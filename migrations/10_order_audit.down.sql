@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_audit;
+
+DROP FUNCTION IF EXISTS order_audit_append_only();
@@ -0,0 +1,25 @@
+-- Журнал аудита заказов. Записи не ссылаются на order_keys, чтобы пережить безвозвратное удаление заказа.
+CREATE TABLE order_audit (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    source VARCHAR(16) NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    version BIGINT NOT NULL,
+    before JSONB,
+    after JSONB,
+    changed_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX order_audit_order_uid_idx ON order_audit (order_uid, id);
+
+-- Журнал только дописывается
+CREATE FUNCTION order_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'order_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER order_audit_append_only
+    BEFORE UPDATE OR DELETE ON order_audit
+    FOR EACH ROW EXECUTE FUNCTION order_audit_append_only();
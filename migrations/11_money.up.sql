@@ -0,0 +1,74 @@
+-- Суммы хранятся в минимальных единицах валюты платежа; INTEGER переполняется уже на ~21 млн при трех знаках
+ALTER TABLE payment
+    ALTER COLUMN amount TYPE BIGINT,
+    ALTER COLUMN delivery_cost TYPE BIGINT,
+    ALTER COLUMN goods_total TYPE BIGINT,
+    ALTER COLUMN custom_fee TYPE BIGINT;
+
+ALTER TABLE item
+    ALTER COLUMN price TYPE BIGINT,
+    ALTER COLUMN total_price TYPE BIGINT;
+
+-- Код валюты ISO 4217. Существующие строки не проверяются, чтобы миграция не падала на старых данных.
+ALTER TABLE payment ADD CONSTRAINT payment_currency_check CHECK (currency ~ '^[A-Z]{3}$') NOT VALID;
+
+-- Сохраненные заказы хранят суммы в основных единицах. Умножаем их на 10^exponent валюты платежа
+-- (exponent - число знаков после запятой по ISO 4217, как в models.Currency.Exponent).
+CREATE FUNCTION currency_minor_factor(currency TEXT) RETURNS BIGINT AS $$
+    SELECT CASE
+        WHEN currency IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+                          'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+        WHEN currency IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        WHEN currency IN ('CLF', 'UYW') THEN 10000
+        ELSE 100
+    END
+$$ LANGUAGE sql IMMUTABLE;
+
+UPDATE item i
+SET price = i.price * currency_minor_factor(p.currency),
+    total_price = i.total_price * currency_minor_factor(p.currency)
+FROM orders o
+JOIN payment p ON p.id = o.payment_id
+WHERE i.order_uid = o.order_uid AND i.date_created = o.date_created;
+
+UPDATE payment
+SET amount = amount * currency_minor_factor(currency),
+    delivery_cost = delivery_cost * currency_minor_factor(currency),
+    goods_total = goods_total * currency_minor_factor(currency),
+    custom_fee = custom_fee * currency_minor_factor(currency);
+
+-- Новая версия заказа сбрасывает ETag и кеш, восстановленный из снимка
+UPDATE orders SET version = version + 1;
+
+-- Исходные сообщения не переписываются: major_units отмечает сообщения с суммами в основных единицах,
+-- их пересчитывает повторная нормализация. Новые сообщения приходят в минимальных единицах.
+ALTER TABLE order_raw ADD COLUMN major_units BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE order_raw ALTER COLUMN major_units SET DEFAULT false;
+
+-- Отсоединенные секции пересчитываются так же: товары в item_pYYYYMM, оплата и исходные
+-- сообщения в order_details_pYYYYMM
+DO $$
+DECLARE
+    month TEXT;
+BEGIN
+    FOR month IN SELECT substr(relname, 16) FROM pg_class WHERE relkind = 'r' AND relname ~ '^order_details_p[0-9]{6}$' LOOP
+        IF to_regclass('item_p' || month) IS NOT NULL THEN
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN price TYPE BIGINT, ALTER COLUMN total_price TYPE BIGINT', 'item_p' || month);
+            EXECUTE format('UPDATE %I i
+                SET price = i.price * currency_minor_factor(d.payment->>''currency''),
+                    total_price = i.total_price * currency_minor_factor(d.payment->>''currency'')
+                FROM %I d WHERE i.order_uid = d.order_uid', 'item_p' || month, 'order_details_p' || month);
+        END IF;
+        EXECUTE format('UPDATE %I SET
+            payment = payment || jsonb_build_object(
+                ''amount'', (payment->>''amount'')::BIGINT * currency_minor_factor(payment->>''currency''),
+                ''delivery_cost'', (payment->>''delivery_cost'')::BIGINT * currency_minor_factor(payment->>''currency''),
+                ''goods_total'', (payment->>''goods_total'')::BIGINT * currency_minor_factor(payment->>''currency''),
+                ''custom_fee'', (payment->>''custom_fee'')::BIGINT * currency_minor_factor(payment->>''currency'')),
+            raw_orders = (SELECT jsonb_agg(r || ''{"major_units": true}'' ORDER BY n)
+                FROM jsonb_array_elements(raw_orders) WITH ORDINALITY AS e(r, n))', 'order_details_p' || month);
+    END LOOP;
+END
+$$;
+
+DROP FUNCTION currency_minor_factor(TEXT);
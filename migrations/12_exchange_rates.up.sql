@@ -0,0 +1,8 @@
+-- Курсы валют: цена одной основной единицы валюты в рублях, действует с rate_date до следующей загруженной даты
+CREATE TABLE exchange_rates (
+    currency VARCHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(24, 10) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (currency, rate_date)
+);
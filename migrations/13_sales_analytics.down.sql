@@ -0,0 +1,2 @@
+DROP MATERIALIZED VIEW IF EXISTS sales_brand_daily;
+DROP MATERIALIZED VIEW IF EXISTS sales_daily;
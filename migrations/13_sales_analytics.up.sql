@@ -0,0 +1,34 @@
+-- Дневные агрегаты продаж для /analytics. Обновляются фоновой задачей через
+-- REFRESH MATERIALIZED VIEW CONCURRENTLY, поэтому у каждого представления есть уникальный индекс.
+-- Удаленные, отмененные (900) и возвращенные (910) заказы не учитываются.
+CREATE MATERIALIZED VIEW sales_daily AS
+SELECT o.date_created::date AS day,
+       p.currency,
+       o.delivery_service,
+       p.provider,
+       d.region,
+       count(*)::bigint AS orders,
+       sum(p.amount)::bigint AS revenue,
+       sum(p.delivery_cost)::bigint AS delivery_cost
+FROM orders o
+JOIN payment p ON p.id = o.payment_id
+JOIN delivery d ON d.id = o.delivery_id
+WHERE o.deleted_at IS NULL AND o.status NOT IN (900, 910)
+GROUP BY 1, 2, 3, 4, 5;
+
+CREATE UNIQUE INDEX sales_daily_key_idx ON sales_daily (day, currency, delivery_service, provider, region);
+
+-- Выручка бренда - сумма total_price его товаров, заказ учитывается в каждом бренде из своего состава
+CREATE MATERIALIZED VIEW sales_brand_daily AS
+SELECT i.date_created::date AS day,
+       p.currency,
+       i.brand,
+       count(DISTINCT i.order_uid)::bigint AS orders,
+       sum(i.total_price)::bigint AS revenue
+FROM item i
+JOIN orders o ON o.order_uid = i.order_uid AND o.date_created = i.date_created
+JOIN payment p ON p.id = o.payment_id
+WHERE o.deleted_at IS NULL AND o.status NOT IN (900, 910)
+GROUP BY 1, 2, 3;
+
+CREATE UNIQUE INDEX sales_brand_daily_key_idx ON sales_brand_daily (day, currency, brand);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_customer_id_date_created_idx;
+CREATE INDEX orders_customer_id_idx ON orders (customer_id);
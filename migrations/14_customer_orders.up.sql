@@ -0,0 +1,3 @@
+-- Заказы покупателя выбираются страницами в порядке (date_created, order_uid)
+DROP INDEX IF EXISTS orders_customer_id_idx;
+CREATE INDEX orders_customer_id_date_created_idx ON orders (customer_id, date_created, order_uid);
@@ -0,0 +1,13 @@
+-- Ключи идемпотентности HTTP API и сохраненные ответы. Истекшие ключи удаляются фоновой задачей
+-- и могут быть использованы повторно.
+CREATE TABLE idempotency_keys (
+    client VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (client, key)
+);
+
+CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys (created_at);
@@ -0,0 +1,2 @@
+-- Скрытые данные не восстанавливаются
+DROP TRIGGER IF EXISTS order_audit_no_truncate ON order_audit;
@@ -0,0 +1,34 @@
+-- Журнал аудита хранится бессрочно и переживает очистку заказа, поэтому персональные и платежные
+-- данные в нем скрываются. Новые записи содержат только измененные поля, старые записи создания
+-- и перезаписи с полным заказом очищаются здесь.
+CREATE FUNCTION order_audit_redact(payload JSONB) RETURNS JSONB AS $$
+BEGIN
+    IF jsonb_typeof(payload->'delivery') = 'object' THEN
+        payload := jsonb_set(payload, '{delivery}', (
+            SELECT COALESCE(jsonb_object_agg(key, '"[redacted]"'::jsonb), '{}'::jsonb)
+            FROM jsonb_object_keys(payload->'delivery') AS key
+        ));
+    END IF;
+    IF payload ? 'customer_id' THEN
+        payload := jsonb_set(payload, '{customer_id}', '"[redacted]"');
+    END IF;
+    payload := jsonb_set(payload, '{payment,transaction}', '"[redacted]"', false);
+    payload := jsonb_set(payload, '{payment,request_id}', '"[redacted]"', false);
+    RETURN payload;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE order_audit DISABLE TRIGGER order_audit_append_only;
+
+UPDATE order_audit
+SET before = order_audit_redact(before), after = order_audit_redact(after)
+WHERE action IN ('create', 'update');
+
+ALTER TABLE order_audit ENABLE TRIGGER order_audit_append_only;
+
+DROP FUNCTION order_audit_redact(JSONB);
+
+-- TRUNCATE не вызывает строковые триггеры, запрещаем его отдельно
+CREATE TRIGGER order_audit_no_truncate
+    BEFORE TRUNCATE ON order_audit
+    FOR EACH STATEMENT EXECUTE FUNCTION order_audit_append_only();
@@ -0,0 +1,9 @@
+-- Из нескольких сообщений HTTP API остается самое раннее
+DELETE FROM order_raw r
+USING order_raw earlier
+WHERE r.topic = 'http' AND earlier.topic = 'http'
+    AND earlier.kafka_partition = r.kafka_partition AND earlier.kafka_offset = r.kafka_offset
+    AND earlier.id < r.id;
+
+DROP INDEX IF EXISTS order_raw_message_idx;
+CREATE UNIQUE INDEX order_raw_message_idx ON order_raw (topic, kafka_partition, kafka_offset) WHERE topic <> '';
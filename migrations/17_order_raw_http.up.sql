@@ -0,0 +1,5 @@
+-- Сообщения, принятые через HTTP API (topic = 'http'), не имеют partition/offset: уникальный индекс
+-- по сообщению Kafka отбрасывал все такие сообщения, кроме первого
+DROP INDEX IF EXISTS order_raw_message_idx;
+CREATE UNIQUE INDEX order_raw_message_idx ON order_raw (topic, kafka_partition, kafka_offset)
+    WHERE topic <> '' AND topic <> 'http';
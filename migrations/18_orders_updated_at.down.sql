@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS order_audit_purge_idx;
+DROP TRIGGER IF EXISTS orders_set_updated_at ON orders;
+DROP FUNCTION IF EXISTS orders_set_updated_at();
+DROP INDEX IF EXISTS orders_updated_at_idx;
+ALTER TABLE orders DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,18 @@
+-- Время последнего изменения заказа: по нему сверка кеша читает только измененные заказы
+ALTER TABLE orders ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT now();
+
+CREATE INDEX orders_updated_at_idx ON orders (updated_at);
+
+CREATE FUNCTION orders_set_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_set_updated_at
+    BEFORE UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION orders_set_updated_at();
+
+-- Безвозвратно удаленные и перенесенные в архив заказы сверка находит по журналу аудита
+CREATE INDEX order_audit_purge_idx ON order_audit (changed_at) WHERE action = 'purge';
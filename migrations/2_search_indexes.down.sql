@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS item_chrt_id_idx;
+DROP INDEX IF EXISTS item_nm_id_idx;
+DROP INDEX IF EXISTS item_brand_idx;
+
+DROP INDEX IF EXISTS payment_bank_idx;
+DROP INDEX IF EXISTS payment_provider_idx;
+
+DROP INDEX IF EXISTS delivery_email_lower_idx;
+DROP INDEX IF EXISTS delivery_phone_idx;
+
+DROP INDEX IF EXISTS orders_payment_id_idx;
+DROP INDEX IF EXISTS orders_delivery_id_idx;
+DROP INDEX IF EXISTS orders_delivery_service_idx;
+DROP INDEX IF EXISTS orders_customer_id_idx;
+DROP INDEX IF EXISTS orders_date_created_order_uid_idx;
@@ -0,0 +1,15 @@
+CREATE INDEX IF NOT EXISTS orders_date_created_order_uid_idx ON orders (date_created, order_uid);
+CREATE INDEX IF NOT EXISTS orders_customer_id_idx ON orders (customer_id);
+CREATE INDEX IF NOT EXISTS orders_delivery_service_idx ON orders (delivery_service);
+CREATE INDEX IF NOT EXISTS orders_delivery_id_idx ON orders (delivery_id);
+CREATE INDEX IF NOT EXISTS orders_payment_id_idx ON orders (payment_id);
+
+CREATE INDEX IF NOT EXISTS delivery_phone_idx ON delivery (phone);
+CREATE INDEX IF NOT EXISTS delivery_email_lower_idx ON delivery (lower(email));
+
+CREATE INDEX IF NOT EXISTS payment_provider_idx ON payment (provider);
+CREATE INDEX IF NOT EXISTS payment_bank_idx ON payment (bank);
+
+CREATE INDEX IF NOT EXISTS item_brand_idx ON item (brand);
+CREATE INDEX IF NOT EXISTS item_nm_id_idx ON item (nm_id);
+CREATE INDEX IF NOT EXISTS item_chrt_id_idx ON item (chrt_id);
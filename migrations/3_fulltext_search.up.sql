@@ -0,0 +1,21 @@
+-- Поисковый документ заказа: товары (вес A) и доставка (вес B)
+CREATE TABLE order_search (
+    order_uid VARCHAR(255) PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE,
+    document TEXT NOT NULL,
+    search_vector TSVECTOR NOT NULL
+);
+
+CREATE INDEX order_search_vector_idx ON order_search USING GIN (search_vector);
+
+INSERT INTO order_search (order_uid, document, search_vector)
+SELECT o.order_uid,
+       concat_ws(' ', items.text, d.name, d.city, d.address),
+       setweight(to_tsvector('simple', coalesce(items.text, '')), 'A') ||
+       setweight(to_tsvector('simple', concat_ws(' ', d.name, d.city, d.address)), 'B')
+FROM orders o
+JOIN delivery d ON d.id = o.delivery_id
+LEFT JOIN LATERAL (
+    SELECT string_agg(concat_ws(' ', i.brand, i.name), ' ' ORDER BY i.id) AS text
+    FROM item i
+    WHERE i.order_uid = o.order_uid
+) items ON true;
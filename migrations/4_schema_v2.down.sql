@@ -0,0 +1,60 @@
+DROP INDEX IF EXISTS item_order_uid_idx;
+
+ALTER TABLE item
+    DROP CONSTRAINT IF EXISTS item_price_check,
+    DROP CONSTRAINT IF EXISTS item_name_check,
+    ALTER COLUMN chrt_id DROP NOT NULL, ALTER COLUMN chrt_id DROP DEFAULT,
+    ALTER COLUMN track_number DROP NOT NULL, ALTER COLUMN track_number DROP DEFAULT,
+    ALTER COLUMN price DROP NOT NULL,
+    ALTER COLUMN rid DROP NOT NULL, ALTER COLUMN rid DROP DEFAULT,
+    ALTER COLUMN name DROP NOT NULL,
+    ALTER COLUMN sale DROP NOT NULL, ALTER COLUMN sale DROP DEFAULT,
+    ALTER COLUMN size DROP NOT NULL, ALTER COLUMN size DROP DEFAULT,
+    ALTER COLUMN total_price DROP NOT NULL, ALTER COLUMN total_price DROP DEFAULT,
+    ALTER COLUMN nm_id DROP NOT NULL, ALTER COLUMN nm_id DROP DEFAULT,
+    ALTER COLUMN brand DROP NOT NULL, ALTER COLUMN brand DROP DEFAULT,
+    ALTER COLUMN status DROP NOT NULL, ALTER COLUMN status DROP DEFAULT,
+    ALTER COLUMN order_uid DROP NOT NULL;
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_order_uid_check,
+    ALTER COLUMN entry DROP NOT NULL, ALTER COLUMN entry DROP DEFAULT,
+    ALTER COLUMN delivery_id DROP NOT NULL,
+    ALTER COLUMN payment_id DROP NOT NULL,
+    ALTER COLUMN locale DROP NOT NULL, ALTER COLUMN locale DROP DEFAULT,
+    ALTER COLUMN internal_signature DROP NOT NULL, ALTER COLUMN internal_signature DROP DEFAULT,
+    ALTER COLUMN customer_id DROP NOT NULL, ALTER COLUMN customer_id DROP DEFAULT,
+    ALTER COLUMN delivery_service DROP NOT NULL, ALTER COLUMN delivery_service DROP DEFAULT,
+    ALTER COLUMN shardkey DROP NOT NULL, ALTER COLUMN shardkey DROP DEFAULT,
+    ALTER COLUMN sm_id DROP NOT NULL, ALTER COLUMN sm_id DROP DEFAULT,
+    ALTER COLUMN date_created DROP NOT NULL, ALTER COLUMN date_created DROP DEFAULT,
+    ALTER COLUMN oof_shard DROP NOT NULL, ALTER COLUMN oof_shard DROP DEFAULT;
+
+ALTER TABLE payment
+    DROP CONSTRAINT IF EXISTS payment_amount_check,
+    DROP CONSTRAINT IF EXISTS payment_provider_check,
+    DROP CONSTRAINT IF EXISTS payment_transaction_check,
+    ALTER COLUMN transaction DROP NOT NULL,
+    ALTER COLUMN request_id DROP NOT NULL, ALTER COLUMN request_id DROP DEFAULT,
+    ALTER COLUMN currency DROP NOT NULL, ALTER COLUMN currency DROP DEFAULT,
+    ALTER COLUMN provider DROP NOT NULL,
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN payment_dt DROP NOT NULL, ALTER COLUMN payment_dt DROP DEFAULT,
+    ALTER COLUMN bank DROP NOT NULL, ALTER COLUMN bank DROP DEFAULT,
+    ALTER COLUMN delivery_cost DROP NOT NULL, ALTER COLUMN delivery_cost DROP DEFAULT,
+    ALTER COLUMN goods_total DROP NOT NULL, ALTER COLUMN goods_total DROP DEFAULT,
+    ALTER COLUMN custom_fee DROP NOT NULL, ALTER COLUMN custom_fee DROP DEFAULT;
+
+ALTER TABLE delivery
+    DROP CONSTRAINT IF EXISTS delivery_phone_check,
+    DROP CONSTRAINT IF EXISTS delivery_name_check,
+    ALTER COLUMN name DROP NOT NULL,
+    ALTER COLUMN phone DROP NOT NULL,
+    ALTER COLUMN zip DROP NOT NULL, ALTER COLUMN zip DROP DEFAULT,
+    ALTER COLUMN city DROP NOT NULL, ALTER COLUMN city DROP DEFAULT,
+    ALTER COLUMN address DROP NOT NULL, ALTER COLUMN address DROP DEFAULT,
+    ALTER COLUMN region DROP NOT NULL, ALTER COLUMN region DROP DEFAULT,
+    ALTER COLUMN email DROP NOT NULL, ALTER COLUMN email DROP DEFAULT;
+
+-- Восстановление уникальности упадет, если у разных заказов совпадает email
+ALTER TABLE delivery ADD CONSTRAINT delivery_email_key UNIQUE (email);
@@ -0,0 +1,126 @@
+-- Email доставки не уникален: один покупатель может сделать несколько заказов
+ALTER TABLE delivery DROP CONSTRAINT IF EXISTS delivery_email_key;
+
+-- Строки, которые нельзя привести к новым ограничениям, миграция не удаляет, а останавливается:
+-- их нужно исправить или удалить вручную и запустить миграцию снова
+DO $$
+DECLARE
+    items_without_order bigint;
+    orders_without_refs bigint;
+    invalid_rows bigint;
+BEGIN
+    SELECT count(*) INTO items_without_order FROM item WHERE order_uid IS NULL;
+    SELECT count(*) INTO orders_without_refs FROM orders WHERE delivery_id IS NULL OR payment_id IS NULL;
+    SELECT
+        (SELECT count(*) FROM delivery WHERE coalesce(name, '') = '' OR coalesce(phone, '') = '') +
+        (SELECT count(*) FROM payment WHERE coalesce(transaction, '') = '' OR coalesce(provider, '') = '' OR coalesce(amount, 0) <= 0) +
+        (SELECT count(*) FROM item WHERE coalesce(name, '') = '' OR coalesce(price, 0) <= 0) +
+        (SELECT count(*) FROM orders WHERE order_uid = '')
+    INTO invalid_rows;
+
+    IF items_without_order + orders_without_refs + invalid_rows > 0 THEN
+        RAISE EXCEPTION 'schema v2 cannot be applied: % items without order_uid, % orders without delivery or payment, % rows with empty required fields; fix or remove them and run the migration again',
+            items_without_order, orders_without_refs, invalid_rows;
+    END IF;
+END
+$$;
+
+-- Данные: пустые значения приводятся к новым ограничениям
+
+UPDATE delivery SET
+    zip = coalesce(zip, ''),
+    city = coalesce(city, ''),
+    address = coalesce(address, ''),
+    region = coalesce(region, ''),
+    email = coalesce(email, '');
+
+UPDATE payment SET
+    request_id = coalesce(request_id, ''),
+    currency = coalesce(currency, ''),
+    payment_dt = coalesce(payment_dt, 0),
+    bank = coalesce(bank, ''),
+    delivery_cost = coalesce(delivery_cost, 0),
+    goods_total = coalesce(goods_total, 0),
+    custom_fee = coalesce(custom_fee, 0);
+
+UPDATE orders SET
+    entry = coalesce(entry, ''),
+    locale = coalesce(locale, ''),
+    internal_signature = coalesce(internal_signature, ''),
+    customer_id = coalesce(customer_id, ''),
+    delivery_service = coalesce(delivery_service, ''),
+    shardkey = coalesce(shardkey, ''),
+    sm_id = coalesce(sm_id, 0),
+    date_created = coalesce(date_created, now()),
+    oof_shard = coalesce(oof_shard, '');
+
+UPDATE item SET
+    chrt_id = coalesce(chrt_id, 0),
+    track_number = coalesce(track_number, ''),
+    rid = coalesce(rid, ''),
+    sale = coalesce(sale, 0),
+    size = coalesce(size, ''),
+    total_price = coalesce(total_price, 0),
+    nm_id = coalesce(nm_id, 0),
+    brand = coalesce(brand, ''),
+    status = coalesce(status, 0);
+
+-- Ограничения повторяют правила валидации models.Order
+ALTER TABLE delivery
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN phone SET NOT NULL,
+    ALTER COLUMN zip SET DEFAULT '', ALTER COLUMN zip SET NOT NULL,
+    ALTER COLUMN city SET DEFAULT '', ALTER COLUMN city SET NOT NULL,
+    ALTER COLUMN address SET DEFAULT '', ALTER COLUMN address SET NOT NULL,
+    ALTER COLUMN region SET DEFAULT '', ALTER COLUMN region SET NOT NULL,
+    ALTER COLUMN email SET DEFAULT '', ALTER COLUMN email SET NOT NULL,
+    ADD CONSTRAINT delivery_name_check CHECK (name <> ''),
+    ADD CONSTRAINT delivery_phone_check CHECK (phone <> '');
+
+ALTER TABLE payment
+    ALTER COLUMN transaction SET NOT NULL,
+    ALTER COLUMN request_id SET DEFAULT '', ALTER COLUMN request_id SET NOT NULL,
+    ALTER COLUMN currency SET DEFAULT '', ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN provider SET NOT NULL,
+    ALTER COLUMN amount SET NOT NULL,
+    ALTER COLUMN payment_dt SET DEFAULT 0, ALTER COLUMN payment_dt SET NOT NULL,
+    ALTER COLUMN bank SET DEFAULT '', ALTER COLUMN bank SET NOT NULL,
+    ALTER COLUMN delivery_cost SET DEFAULT 0, ALTER COLUMN delivery_cost SET NOT NULL,
+    ALTER COLUMN goods_total SET DEFAULT 0, ALTER COLUMN goods_total SET NOT NULL,
+    ALTER COLUMN custom_fee SET DEFAULT 0, ALTER COLUMN custom_fee SET NOT NULL,
+    ADD CONSTRAINT payment_transaction_check CHECK (transaction <> ''),
+    ADD CONSTRAINT payment_provider_check CHECK (provider <> ''),
+    ADD CONSTRAINT payment_amount_check CHECK (amount > 0);
+
+ALTER TABLE orders
+    ALTER COLUMN entry SET DEFAULT '', ALTER COLUMN entry SET NOT NULL,
+    ALTER COLUMN delivery_id SET NOT NULL,
+    ALTER COLUMN payment_id SET NOT NULL,
+    ALTER COLUMN locale SET DEFAULT '', ALTER COLUMN locale SET NOT NULL,
+    ALTER COLUMN internal_signature SET DEFAULT '', ALTER COLUMN internal_signature SET NOT NULL,
+    ALTER COLUMN customer_id SET DEFAULT '', ALTER COLUMN customer_id SET NOT NULL,
+    ALTER COLUMN delivery_service SET DEFAULT '', ALTER COLUMN delivery_service SET NOT NULL,
+    ALTER COLUMN shardkey SET DEFAULT '', ALTER COLUMN shardkey SET NOT NULL,
+    ALTER COLUMN sm_id SET DEFAULT 0, ALTER COLUMN sm_id SET NOT NULL,
+    ALTER COLUMN date_created SET DEFAULT now(), ALTER COLUMN date_created SET NOT NULL,
+    ALTER COLUMN oof_shard SET DEFAULT '', ALTER COLUMN oof_shard SET NOT NULL,
+    ADD CONSTRAINT orders_order_uid_check CHECK (order_uid <> '');
+
+ALTER TABLE item
+    ALTER COLUMN chrt_id SET DEFAULT 0, ALTER COLUMN chrt_id SET NOT NULL,
+    ALTER COLUMN track_number SET DEFAULT '', ALTER COLUMN track_number SET NOT NULL,
+    ALTER COLUMN price SET NOT NULL,
+    ALTER COLUMN rid SET DEFAULT '', ALTER COLUMN rid SET NOT NULL,
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN sale SET DEFAULT 0, ALTER COLUMN sale SET NOT NULL,
+    ALTER COLUMN size SET DEFAULT '', ALTER COLUMN size SET NOT NULL,
+    ALTER COLUMN total_price SET DEFAULT 0, ALTER COLUMN total_price SET NOT NULL,
+    ALTER COLUMN nm_id SET DEFAULT 0, ALTER COLUMN nm_id SET NOT NULL,
+    ALTER COLUMN brand SET DEFAULT '', ALTER COLUMN brand SET NOT NULL,
+    ALTER COLUMN status SET DEFAULT 0, ALTER COLUMN status SET NOT NULL,
+    ALTER COLUMN order_uid SET NOT NULL,
+    ADD CONSTRAINT item_name_check CHECK (name <> ''),
+    ADD CONSTRAINT item_price_check CHECK (price > 0);
+
+-- Товары всегда читаются по заказу в порядке вставки
+CREATE INDEX IF NOT EXISTS item_order_uid_idx ON item (order_uid, id);
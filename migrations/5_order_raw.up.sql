@@ -0,0 +1,16 @@
+-- Исходные сообщения, из которых получены заказы. Связь с orders логическая (без FK),
+-- чтобы сохранять и сообщения, которые не удалось нормализовать.
+CREATE TABLE order_raw (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    topic VARCHAR(255) NOT NULL DEFAULT '',
+    kafka_partition INTEGER NOT NULL DEFAULT 0,
+    kafka_offset BIGINT NOT NULL DEFAULT 0,
+    ingested_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX order_raw_order_uid_idx ON order_raw (order_uid, id);
+
+-- Повторная доставка того же сообщения Kafka не создает дубликат
+CREATE UNIQUE INDEX order_raw_message_idx ON order_raw (topic, kafka_partition, kafka_offset) WHERE topic <> '';
@@ -0,0 +1,32 @@
+-- Текущий статус заказа, коды см. models.Status
+ALTER TABLE orders ADD COLUMN status INTEGER NOT NULL DEFAULT 100;
+
+CREATE TABLE order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    from_status INTEGER NOT NULL DEFAULT 0,
+    to_status INTEGER NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX order_status_history_order_uid_idx ON order_status_history (order_uid, id);
+
+-- Существующие заказы получают общий статус своих товаров, если он у всех товаров одинаковый
+-- и известен (см. models.Order.NormalizeStatus), иначе остаются в статусе 100
+UPDATE orders o SET status = s.status
+FROM (
+    SELECT order_uid, min(status) AS status FROM item
+    GROUP BY order_uid
+    HAVING count(DISTINCT status) = 1 AND count(status) = count(*) AND min(status) IN (100, 202, 300, 400, 500, 900, 910)
+) s
+WHERE s.order_uid = o.order_uid;
+
+-- Статус товаров всегда совпадает со статусом заказа
+UPDATE item i SET status = o.status
+FROM orders o
+WHERE o.order_uid = i.order_uid AND i.status IS DISTINCT FROM o.status;
+
+-- Существующие заказы получают начальную запись истории на момент создания
+INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at)
+SELECT order_uid, 0, status, date_created FROM orders;
@@ -0,0 +1,4 @@
+-- Мягкое удаление: заказ скрыт из чтения, но данные сохраняются до восстановления или очистки
+ALTER TABLE orders ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX orders_deleted_at_idx ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
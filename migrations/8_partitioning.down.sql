@@ -0,0 +1,94 @@
+-- Возврат к обычным таблицам. Отсоединенные секции не возвращаются: их нужно
+-- присоединить обратно (ATTACH PARTITION) до отката.
+ALTER TABLE item RENAME TO item_part;
+ALTER TABLE orders RENAME TO orders_part;
+ALTER SEQUENCE item_id_seq OWNED BY NONE;
+
+-- Первичные и уникальные ключи добавляются после удаления секционированных таблиц,
+-- чтобы не конфликтовать с именами их индексов
+CREATE TABLE orders (
+    order_uid VARCHAR(255) NOT NULL,
+    track_number VARCHAR(255),
+    entry VARCHAR(100) NOT NULL DEFAULT '',
+    delivery_id INTEGER NOT NULL REFERENCES delivery(id) ON DELETE CASCADE,
+    payment_id INTEGER NOT NULL REFERENCES payment(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL DEFAULT '',
+    internal_signature VARCHAR(255) NOT NULL DEFAULT '',
+    customer_id VARCHAR(255) NOT NULL DEFAULT '',
+    delivery_service VARCHAR(100) NOT NULL DEFAULT '',
+    shardkey VARCHAR(50) NOT NULL DEFAULT '',
+    sm_id INTEGER NOT NULL DEFAULT 0,
+    date_created TIMESTAMP NOT NULL DEFAULT now(),
+    oof_shard VARCHAR(50) NOT NULL DEFAULT '',
+    status INTEGER NOT NULL DEFAULT 100,
+    deleted_at TIMESTAMP,
+    CONSTRAINT orders_order_uid_check CHECK (order_uid <> '')
+);
+
+CREATE TABLE item (
+    id INTEGER NOT NULL DEFAULT nextval('item_id_seq'),
+    chrt_id INTEGER NOT NULL DEFAULT 0,
+    track_number VARCHAR(255) NOT NULL DEFAULT '',
+    price INTEGER NOT NULL,
+    rid VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL,
+    sale INTEGER NOT NULL DEFAULT 0,
+    size VARCHAR(50) NOT NULL DEFAULT '',
+    total_price INTEGER NOT NULL DEFAULT 0,
+    nm_id INTEGER NOT NULL DEFAULT 0,
+    brand VARCHAR(255) NOT NULL DEFAULT '',
+    status INTEGER NOT NULL DEFAULT 0,
+    order_uid VARCHAR(255) NOT NULL,
+    CONSTRAINT item_name_check CHECK (name <> ''),
+    CONSTRAINT item_price_check CHECK (price > 0)
+);
+
+INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id,
+                    delivery_service, shardkey, sm_id, date_created, oof_shard, status, deleted_at)
+SELECT order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id,
+       delivery_service, shardkey, sm_id, date_created, oof_shard, status, deleted_at
+FROM orders_part;
+
+INSERT INTO item (id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid)
+SELECT id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid
+FROM item_part;
+
+DROP TABLE item_part;
+DROP TABLE orders_part;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (order_uid),
+    ADD CONSTRAINT orders_track_number_key UNIQUE (track_number);
+
+ALTER TABLE item
+    ADD CONSTRAINT item_pkey PRIMARY KEY (id),
+    ADD CONSTRAINT item_order_uid_fkey FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE;
+
+ALTER SEQUENCE item_id_seq AS INTEGER;
+ALTER SEQUENCE item_id_seq OWNED BY item.id;
+
+-- Зависимые таблицы снова ссылаются на orders. Строки реестра без заказа (из отсоединенных секций) удаляются.
+DELETE FROM order_search s WHERE NOT EXISTS (SELECT 1 FROM orders o WHERE o.order_uid = s.order_uid);
+DELETE FROM order_status_history h WHERE NOT EXISTS (SELECT 1 FROM orders o WHERE o.order_uid = h.order_uid);
+
+ALTER TABLE order_search
+    DROP CONSTRAINT order_search_order_uid_fkey,
+    ADD CONSTRAINT order_search_order_uid_fkey FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE;
+
+ALTER TABLE order_status_history
+    DROP CONSTRAINT order_status_history_order_uid_fkey,
+    ADD CONSTRAINT order_status_history_order_uid_fkey FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE;
+
+DROP TABLE order_keys;
+
+CREATE INDEX orders_date_created_order_uid_idx ON orders (date_created, order_uid);
+CREATE INDEX orders_customer_id_idx ON orders (customer_id);
+CREATE INDEX orders_delivery_service_idx ON orders (delivery_service);
+CREATE INDEX orders_delivery_id_idx ON orders (delivery_id);
+CREATE INDEX orders_payment_id_idx ON orders (payment_id);
+CREATE INDEX orders_deleted_at_idx ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX item_order_uid_idx ON item (order_uid, id);
+CREATE INDEX item_brand_idx ON item (brand);
+CREATE INDEX item_nm_id_idx ON item (nm_id);
+CREATE INDEX item_chrt_id_idx ON item (chrt_id);
@@ -0,0 +1,123 @@
+-- Реестр ключей заказов. Секционированная таблица не может обеспечить глобальную уникальность
+-- order_uid и track_number и быть целью внешних ключей, поэтому это делает реестр. Он же хранит
+-- ключ секционирования date_created, чтобы запросы по order_uid читали только нужную секцию.
+CREATE TABLE order_keys (
+    order_uid VARCHAR(255) PRIMARY KEY,
+    track_number VARCHAR(255) UNIQUE,
+    date_created TIMESTAMP NOT NULL
+);
+
+INSERT INTO order_keys (order_uid, track_number, date_created)
+SELECT order_uid, track_number, date_created FROM orders;
+
+-- Зависимые таблицы ссылаются на реестр вместо orders
+ALTER TABLE order_search
+    DROP CONSTRAINT order_search_order_uid_fkey,
+    ADD CONSTRAINT order_search_order_uid_fkey FOREIGN KEY (order_uid) REFERENCES order_keys(order_uid) ON DELETE CASCADE;
+
+ALTER TABLE order_status_history
+    DROP CONSTRAINT order_status_history_order_uid_fkey,
+    ADD CONSTRAINT order_status_history_order_uid_fkey FOREIGN KEY (order_uid) REFERENCES order_keys(order_uid) ON DELETE CASCADE;
+
+-- Старые таблицы переименовываются, данные переносятся в секционированные
+ALTER TABLE item RENAME TO item_old;
+ALTER TABLE orders RENAME TO orders_old;
+ALTER SEQUENCE item_id_seq OWNED BY NONE;
+ALTER SEQUENCE item_id_seq AS BIGINT;
+
+CREATE TABLE orders (
+    order_uid VARCHAR(255) NOT NULL REFERENCES order_keys(order_uid) ON DELETE CASCADE,
+    track_number VARCHAR(255),
+    entry VARCHAR(100) NOT NULL DEFAULT '',
+    delivery_id INTEGER NOT NULL REFERENCES delivery(id) ON DELETE CASCADE,
+    payment_id INTEGER NOT NULL REFERENCES payment(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL DEFAULT '',
+    internal_signature VARCHAR(255) NOT NULL DEFAULT '',
+    customer_id VARCHAR(255) NOT NULL DEFAULT '',
+    delivery_service VARCHAR(100) NOT NULL DEFAULT '',
+    shardkey VARCHAR(50) NOT NULL DEFAULT '',
+    sm_id INTEGER NOT NULL DEFAULT 0,
+    date_created TIMESTAMP NOT NULL DEFAULT now(),
+    oof_shard VARCHAR(50) NOT NULL DEFAULT '',
+    status INTEGER NOT NULL DEFAULT 100,
+    deleted_at TIMESTAMP,
+    CONSTRAINT orders_order_uid_check CHECK (order_uid <> '')
+) PARTITION BY RANGE (date_created);
+
+-- Товар хранит date_created своего заказа и лежит в секции того же месяца
+CREATE TABLE item (
+    id BIGINT NOT NULL DEFAULT nextval('item_id_seq'),
+    chrt_id INTEGER NOT NULL DEFAULT 0,
+    track_number VARCHAR(255) NOT NULL DEFAULT '',
+    price INTEGER NOT NULL,
+    rid VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL,
+    sale INTEGER NOT NULL DEFAULT 0,
+    size VARCHAR(50) NOT NULL DEFAULT '',
+    total_price INTEGER NOT NULL DEFAULT 0,
+    nm_id INTEGER NOT NULL DEFAULT 0,
+    brand VARCHAR(255) NOT NULL DEFAULT '',
+    status INTEGER NOT NULL DEFAULT 0,
+    order_uid VARCHAR(255) NOT NULL REFERENCES order_keys(order_uid) ON DELETE CASCADE,
+    date_created TIMESTAMP NOT NULL,
+    CONSTRAINT item_name_check CHECK (name <> ''),
+    CONSTRAINT item_price_check CHECK (price > 0)
+) PARTITION BY RANGE (date_created);
+
+-- Месячные секции от самого старого заказа до трех месяцев вперед (имена orders_pYYYYMM, item_pYYYYMM).
+-- Дальнейшие секции создает сервер, заказы вне созданных секций попадают в секцию по умолчанию.
+DO $$
+DECLARE
+    part_month DATE;
+BEGIN
+    FOR part_month IN
+        SELECT generate_series(
+            date_trunc('month', least(coalesce((SELECT min(date_created) FROM orders_old), now()), now())),
+            date_trunc('month', now()) + interval '3 months',
+            interval '1 month')::date
+    LOOP
+        EXECUTE format('CREATE TABLE orders_p%s PARTITION OF orders FOR VALUES FROM (%L) TO (%L)',
+            to_char(part_month, 'YYYYMM'), part_month, part_month + interval '1 month');
+        EXECUTE format('CREATE TABLE item_p%s PARTITION OF item FOR VALUES FROM (%L) TO (%L)',
+            to_char(part_month, 'YYYYMM'), part_month, part_month + interval '1 month');
+    END LOOP;
+END $$;
+
+CREATE TABLE orders_default PARTITION OF orders DEFAULT;
+CREATE TABLE item_default PARTITION OF item DEFAULT;
+
+INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id,
+                    delivery_service, shardkey, sm_id, date_created, oof_shard, status, deleted_at)
+SELECT order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id,
+       delivery_service, shardkey, sm_id, date_created, oof_shard, status, deleted_at
+FROM orders_old;
+
+INSERT INTO item (id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid, date_created)
+SELECT i.id, i.chrt_id, i.track_number, i.price, i.rid, i.name, i.sale, i.size, i.total_price, i.nm_id, i.brand, i.status,
+       i.order_uid, o.date_created
+FROM item_old i
+JOIN orders_old o ON o.order_uid = i.order_uid;
+
+DROP TABLE item_old;
+DROP TABLE orders_old;
+
+ALTER SEQUENCE item_id_seq OWNED BY item.id;
+
+-- Первичные ключи секционированных таблиц обязаны включать ключ секционирования
+ALTER TABLE orders ADD CONSTRAINT orders_pkey PRIMARY KEY (order_uid, date_created);
+ALTER TABLE item ADD CONSTRAINT item_pkey PRIMARY KEY (id, date_created);
+
+CREATE INDEX orders_date_created_order_uid_idx ON orders (date_created, order_uid);
+CREATE INDEX orders_track_number_idx ON orders (track_number);
+CREATE INDEX orders_customer_id_idx ON orders (customer_id);
+CREATE INDEX orders_delivery_service_idx ON orders (delivery_service);
+CREATE INDEX orders_delivery_id_idx ON orders (delivery_id);
+CREATE INDEX orders_payment_id_idx ON orders (payment_id);
+CREATE INDEX orders_deleted_at_idx ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX item_order_uid_idx ON item (order_uid, id);
+CREATE INDEX item_brand_idx ON item (brand);
+CREATE INDEX item_nm_id_idx ON item (nm_id);
+CREATE INDEX item_chrt_id_idx ON item (chrt_id);
+
+CREATE INDEX order_keys_date_created_idx ON order_keys (date_created);
@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS event_version,
+    DROP COLUMN IF EXISTS version;
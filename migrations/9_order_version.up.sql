@@ -0,0 +1,4 @@
+-- Версия заказа для оптимистичной блокировки и версия последнего примененного события источника
+ALTER TABLE orders
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1,
+    ADD COLUMN event_version BIGINT NOT NULL DEFAULT 0;
@@ -7,4 +7,11 @@ var (
 	ErrOrderExists   = errors.New("order already exists")
 	ErrInvalidData   = errors.New("invalid data")
 	ErrDatabaseError = errors.New("database error")
+
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	ErrVersionConflict         = errors.New("order version conflict")
+	ErrRateNotFound            = errors.New("exchange rate not found")
+
+	ErrIdempotencyKeyReused     = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is still in progress")
 )